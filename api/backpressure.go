@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// backpressure bounds in-flight requests with a FIFO queue of waiting ones, limits can be changed at runtime
+type backpressure struct {
+	mu           sync.Mutex
+	maxInFlight  int
+	maxQueue     int
+	queueTimeout time.Duration
+	retryAfter   time.Duration
+	inFlight     int
+	queue        []chan struct{}
+	served       uint64
+	rejected     uint64
+	timedOut     uint64
+}
+
+// dispatch passes free slots to waiting requests, mu must be locked
+func (b *backpressure) dispatch() {
+	for len(b.queue) > 0 && (b.maxInFlight <= 0 || b.inFlight < b.maxInFlight) {
+		b.inFlight++
+		close(b.queue[0])
+		b.queue = b.queue[1:]
+	}
+}
+
+// acquire takes a slot, waiting in the queue if needed; false means the request must be shed
+func (b *backpressure) acquire(ctx context.Context) bool {
+	b.mu.Lock()
+	if b.maxInFlight <= 0 || b.inFlight < b.maxInFlight {
+		b.inFlight++
+		b.mu.Unlock()
+		return true
+	}
+	if len(b.queue) >= b.maxQueue {
+		b.rejected++
+		b.mu.Unlock()
+		return false
+	}
+	ready := make(chan struct{})
+	b.queue = append(b.queue, ready)
+	timeout := b.queueTimeout
+	b.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-ready:
+		return true
+	case <-expired:
+	case <-ctx.Done():
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, ch := range b.queue {
+		if ch == ready {
+			b.queue = append(b.queue[:i], b.queue[i+1:]...)
+			b.timedOut++
+			return false
+		}
+	}
+	// the slot was given while giving up: keep it
+	return true
+}
+
+func (b *backpressure) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inFlight--
+	b.served++
+	b.dispatch()
+}
+
+func (b *backpressure) set(cfg *BackpressureIn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.maxInFlight = cfg.MaxInFlight
+	b.maxQueue = cfg.MaxQueue
+	b.queueTimeout = cfg.queueTimeout
+	b.retryAfter = cfg.retryAfter
+	b.dispatch()
+}
+
+func (b *backpressure) stats() *BackpressureOut {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &BackpressureOut{
+		BackpressureIn: BackpressureIn{
+			MaxInFlight:  b.maxInFlight,
+			MaxQueue:     b.maxQueue,
+			QueueTimeout: b.queueTimeout.String(),
+			RetryAfter:   b.retryAfter.String(),
+		},
+		InFlight: b.inFlight,
+		Queued:   len(b.queue),
+		Served:   b.served,
+		Rejected: b.rejected,
+		TimedOut: b.timedOut,
+	}
+}
+
+// BackpressureMiddleware sheds requests with 503 when in-flight ones and the queue are full
+func (app *App) BackpressureMiddleware(c *gin.Context) {
+	if faultExempt(c.Request.URL.Path) {
+		c.Next()
+		return
+	}
+	if !app.backpressure.acquire(c.Request.Context()) {
+		app.backpressure.mu.Lock()
+		retryAfter := app.backpressure.retryAfter
+		app.backpressure.mu.Unlock()
+		c.Header("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter.Seconds())))))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, &ErrorMessage{"Server is overloaded"})
+		return
+	}
+	defer app.backpressure.release()
+	c.Next()
+}
+
+// GetBackpressure godoc
+// @Summary Get in-flight request limits, queue depth and counters
+// @Produce json
+// @Success 200 {object} BackpressureOut
+// @Router /admin/backpressure [get]
+func (app *App) GetBackpressure(c *gin.Context) {
+	c.JSON(http.StatusOK, app.backpressure.stats())
+}
+
+// SetBackpressure godoc
+// @Summary Change in-flight request limits
+// @Produce json
+// @Param backpressure body BackpressureIn true "Limits"
+// @Success 200 {object} BackpressureOut
+// @Failure 422 {object} ErrorMessage
+// @Router /admin/backpressure [put]
+func (app *App) SetBackpressure(c *gin.Context) {
+	var req BackpressureIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	if err := req.parse(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad limits: " + err.Error()})
+		return
+	}
+	app.backpressure.set(&req)
+	c.JSON(http.StatusOK, app.backpressure.stats())
+}
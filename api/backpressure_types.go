@@ -0,0 +1,47 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+type BackpressureIn struct {
+	// MaxInFlight is a number of concurrent requests, 0 is unlimited
+	MaxInFlight int `json:"maxInFlight"`
+	// MaxQueue is a number of requests waiting for a slot
+	MaxQueue int `json:"maxQueue"`
+	// QueueTimeout like "5s" limits waiting in the queue, empty or 0 waits until the client gives up
+	QueueTimeout string `json:"queueTimeout"`
+	// RetryAfter like "1s" is sent with 503
+	RetryAfter   string `json:"retryAfter"`
+	queueTimeout time.Duration
+	retryAfter   time.Duration
+}
+
+func (s *BackpressureIn) parse() error {
+	if s.MaxInFlight < 0 || s.MaxQueue < 0 {
+		return fmt.Errorf("limits can't be negative")
+	}
+	var err error
+	if len(s.QueueTimeout) > 0 {
+		if s.queueTimeout, err = time.ParseDuration(s.QueueTimeout); err != nil {
+			return fmt.Errorf("can't parse queue timeout: %v", err)
+		}
+	}
+	if len(s.RetryAfter) > 0 {
+		if s.retryAfter, err = time.ParseDuration(s.RetryAfter); err != nil {
+			return fmt.Errorf("can't parse retry after: %v", err)
+		}
+	}
+	return nil
+}
+
+type BackpressureOut struct {
+	BackpressureIn
+	InFlight int `json:"inFlight"`
+	// Queued is the current queue depth
+	Queued   int    `json:"queued"`
+	Served   uint64 `json:"served"`
+	Rejected uint64 `json:"rejected"`
+	TimedOut uint64 `json:"timedOut"`
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"smsgate-mock/data"
+	"smsgate-mock/smsc"
+	"strings"
+)
+
+// batchRejection returns the code and the reason of a message of a batch rejected by Submit
+func batchRejection(c *gin.Context, err error) (string, string) {
+	if _, ok := tooLongError(err); ok {
+		return CodeMessageTooLong, err.Error()
+	}
+	if nameErr, ok := senderNameError(err); ok {
+		return (&SenderNameErrorMessage{}).FromModel(nameErr).Code, err.Error()
+	}
+	if balance, ok := balanceError(err); ok {
+		return (&BalanceErrorMessage{}).FromModel(balance).Code, err.Error()
+	}
+	var limit *smsc.LimitError
+	if errors.As(err, &limit) {
+		return (&LimitErrorMessage{}).FromModel(limit).Code, err.Error()
+	}
+	c.Error(fmt.Errorf("can't save message of batch: %v", err))
+	return CodeInternalError, "internal server error"
+}
+
+// Batch godoc
+// @Summary Create many SMS at once: the same text to phoneNumbers or individual messages
+// @Param batch body BatchIn true "Batch data"
+// @Success 201 {object} BatchOut
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ValidationErrorMessage
+// @Failure 401 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /batch [post]
+func (app *App) Batch(c *gin.Context) {
+	var req BatchIn
+	if app.cfg.StrictValidation {
+		if errs := bindStrict(c, &req); len(errs) > 0 {
+			validationFailed(c, errs)
+			return
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	if (len(req.PhoneNumbers) == 0) == (len(req.Messages) == 0) {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Batch needs either phoneNumbers or messages"})
+		return
+	}
+	items := req.Items()
+	if app.cfg.MaxBatchSize > 0 && len(items) > app.cfg.MaxBatchSize {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{fmt.Sprintf("Batch has %d messages, at most %d are allowed", len(items), app.cfg.MaxBatchSize)})
+		return
+	}
+	sender := &data.Sender{}
+	if err := sender.LoadByLogin(app.db, req.Login); err != nil {
+		c.Error(fmt.Errorf("can't load sender: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find sender"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't send batch due to internal server error"})
+		}
+		return
+	}
+	if sender.Password != req.Password {
+		c.JSON(http.StatusUnauthorized, &ErrorMessage{"Password mismatch"})
+		return
+	}
+	res := &BatchOut{Total: len(items), Results: make([]BatchResultOut, len(items))}
+	msgs := make([]*data.Message, 0, len(items))
+	indexes := make([]int, 0, len(items))
+	for i, item := range items {
+		res.Results[i] = BatchResultOut{Index: i, PhoneNumber: item.PhoneNumber}
+		if app.cfg.StrictValidation {
+			if errs := item.Validate(app.cfg.DefaultCountry); len(errs) > 0 {
+				res.Results[i].Code = CodeValidationFailed
+				res.Results[i].Reason = errs[0].Message
+				res.Results[i].Fields = errs
+				continue
+			}
+			res.Results[i].PhoneNumber = item.PhoneNumber
+		}
+		msg := item.ToModel()
+		msg.Sender = sender
+		msgs = append(msgs, msg)
+		indexes = append(indexes, i)
+	}
+	batch := &data.Batch{SenderUuid: sender.SenderUuid, Total: len(items)}
+	errs, err := app.smsc.SubmitBatch(batch, msgs)
+	if err != nil {
+		c.Error(fmt.Errorf("can't save batch: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't send batch due to internal server error"})
+		return
+	}
+	for j, msg := range msgs {
+		result := &res.Results[indexes[j]]
+		if errs[j] != nil {
+			result.Code, result.Reason = batchRejection(c, errs[j])
+			continue
+		}
+		id := msg.MessageUuid
+		result.Accepted = true
+		result.MessageUuid = &id
+		result.Status = msg.Status
+	}
+	res.BatchUuid = batch.BatchUuid
+	res.Create = batch.Create
+	res.Accepted = len(batch.MessageUuids)
+	res.Rejected = res.Total - res.Accepted
+	c.JSON(http.StatusCreated, res)
+}
+
+// BatchStatus godoc
+// @Summary Get counts of batch messages by status
+// @Produce json
+// @Param batchUuid path string true "Batch ID"
+// @Success 200 {object} BatchStatusOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /batch/{batchUuid} [get]
+func (app *App) BatchStatus(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("batchUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse batch uuid"})
+		return
+	}
+	batch := &data.Batch{}
+	if err := batch.LoadById(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't load batch: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested batch"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load batch due to internal server error"})
+		}
+		return
+	}
+	statuses, err := batch.Statuses(app.db)
+	if err != nil {
+		c.Error(fmt.Errorf("can't count batch statuses: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load batch due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, (&BatchStatusOut{}).FromModel(batch, statuses))
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"github.com/google/uuid"
+	"smsgate-mock/data"
+	"time"
+)
+
+// reasons of rejected batch messages besides the codes of single messages
+const (
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeMessageTooLong   = "MESSAGE_TOO_LONG"
+	CodeInternalError    = "INTERNAL_ERROR"
+)
+
+// BatchMessageIn is an individual message of a batch, empty fields are taken from the batch
+type BatchMessageIn struct {
+	PhoneNumber       string     `json:"phoneNumber"`
+	MessageText       string     `json:"messageText,omitempty"`
+	SenderName        string     `json:"senderName,omitempty"`
+	MessageType       string     `json:"messageType,omitempty"`
+	ExpirationTimeout int        `json:"expirationTimeout,omitempty"`
+	CallbackUrl       string     `json:"callbackUrl,omitempty"`
+	SendAt            *time.Time `json:"sendAt,omitempty"`
+}
+
+// BatchIn has either PhoneNumbers getting the same text or individual Messages
+type BatchIn struct {
+	Login             string           `json:"login"`
+	Password          string           `json:"password"`
+	SenderName        string           `json:"senderName"`
+	MessageType       string           `json:"messageType"`
+	MessageText       string           `json:"messageText,omitempty"`
+	ExpirationTimeout int              `json:"expirationTimeout,omitempty"`
+	CallbackUrl       string           `json:"callbackUrl,omitempty"`
+	Transliterate     *bool            `json:"transliterate,omitempty"`
+	SendAt            *time.Time       `json:"sendAt,omitempty"`
+	PhoneNumbers      []string         `json:"phoneNumbers,omitempty"`
+	Messages          []BatchMessageIn `json:"messages,omitempty"`
+}
+
+// Items returns every message of the batch as a single message request
+func (s *BatchIn) Items() []*MessageIn {
+	shared := MessageIn{
+		Login:             s.Login,
+		Password:          s.Password,
+		SenderName:        s.SenderName,
+		MessageType:       s.MessageType,
+		MessageText:       s.MessageText,
+		ExpirationTimeout: s.ExpirationTimeout,
+		CallbackUrl:       s.CallbackUrl,
+		Transliterate:     s.Transliterate,
+		SendAt:            s.SendAt,
+	}
+	ret := make([]*MessageIn, 0, len(s.PhoneNumbers)+len(s.Messages))
+	for _, phoneNumber := range s.PhoneNumbers {
+		item := shared
+		item.PhoneNumber = phoneNumber
+		ret = append(ret, &item)
+	}
+	for _, msg := range s.Messages {
+		item := shared
+		item.PhoneNumber = msg.PhoneNumber
+		if len(msg.MessageText) > 0 {
+			item.MessageText = msg.MessageText
+		}
+		if len(msg.SenderName) > 0 {
+			item.SenderName = msg.SenderName
+		}
+		if len(msg.MessageType) > 0 {
+			item.MessageType = msg.MessageType
+		}
+		if msg.ExpirationTimeout > 0 {
+			item.ExpirationTimeout = msg.ExpirationTimeout
+		}
+		if len(msg.CallbackUrl) > 0 {
+			item.CallbackUrl = msg.CallbackUrl
+		}
+		if msg.SendAt != nil {
+			item.SendAt = msg.SendAt
+		}
+		ret = append(ret, &item)
+	}
+	return ret
+}
+
+// BatchResultOut is the result of one message of a batch in request order
+type BatchResultOut struct {
+	Index       int        `json:"index"`
+	PhoneNumber string     `json:"phoneNumber"`
+	Accepted    bool       `json:"accepted"`
+	MessageUuid *uuid.UUID `json:"messageUuid,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	// Code and Reason explain why the message was rejected, Fields are set in strict validation mode
+	Code   string       `json:"code,omitempty"`
+	Reason string       `json:"reason,omitempty"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+type BatchOut struct {
+	BatchUuid uuid.UUID        `json:"batchUuid"`
+	Create    time.Time        `json:"created"`
+	Total     int              `json:"total"`
+	Accepted  int              `json:"accepted"`
+	Rejected  int              `json:"rejected"`
+	Results   []BatchResultOut `json:"results"`
+}
+
+type BatchStatusOut struct {
+	BatchUuid  uuid.UUID `json:"batchUuid"`
+	SenderUuid uuid.UUID `json:"senderUuid"`
+	Create     time.Time `json:"created"`
+	Total      int       `json:"total"`
+	Accepted   int       `json:"accepted"`
+	Rejected   int       `json:"rejected"`
+	// Statuses are counts of the accepted messages by their current status
+	Statuses map[string]int `json:"statuses"`
+}
+
+func (s *BatchStatusOut) FromModel(src *data.Batch, statuses map[string]int) *BatchStatusOut {
+	s.BatchUuid = src.BatchUuid
+	s.SenderUuid = src.SenderUuid
+	s.Create = src.Create
+	s.Total = src.Total
+	s.Accepted = len(src.MessageUuids)
+	s.Rejected = src.Total - s.Accepted
+	s.Statuses = statuses
+	return s
+}
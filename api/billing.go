@@ -0,0 +1,226 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"smsgate-mock/data"
+	"smsgate-mock/smsc"
+	"strings"
+)
+
+// balanceError returns the error of Submit if a prepaid sender can't pay for the message
+func balanceError(err error) (*smsc.BalanceError, bool) {
+	var balance *smsc.BalanceError
+	return balance, errors.As(err, &balance)
+}
+
+// loadAccount loads the sender from senderUuid URL parameter, it responds with an error if it can't
+func (app *App) loadAccount(c *gin.Context) (*data.Sender, bool) {
+	id, err := uuid.Parse(c.Param("senderUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse sender uuid"})
+		return nil, false
+	}
+	sender := &data.Sender{}
+	if err := sender.LoadById(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't load sender: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested sender"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load account due to internal server error"})
+		}
+		return nil, false
+	}
+	return sender, true
+}
+
+// GetAccount godoc
+// @Summary Get sender's balance
+// @Produce json
+// @Param senderUuid path string true "Sender ID"
+// @Success 200 {object} AccountOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /account/{senderUuid} [get]
+func (app *App) GetAccount(c *gin.Context) {
+	sender, ok := app.loadAccount(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, (&AccountOut{}).FromModel(sender, app.cfg.Currency))
+}
+
+// TopUp godoc
+// @Summary Add money to sender's balance
+// @Produce json
+// @Param senderUuid path string true "Sender ID"
+// @Param topup body TopUpIn true "Amount"
+// @Success 201 {object} LedgerEntryOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /account/{senderUuid}/topup [post]
+func (app *App) TopUp(c *gin.Context) {
+	sender, ok := app.loadAccount(c)
+	if !ok {
+		return
+	}
+	var req TopUpIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	if req.Amount <= 0 {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Amount must be positive"})
+		return
+	}
+	entry := &data.LedgerEntry{}
+	if err := entry.TopUp(app.db, sender.SenderUuid, req.Amount, req.Description); err != nil {
+		c.Error(fmt.Errorf("can't top up balance: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't top up balance due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusCreated, (&LedgerEntryOut{}).FromModel(entry))
+}
+
+// ListLedger godoc
+// @Summary List debits, refunds and top-ups of sender's balance in chronological order
+// @Produce json
+// @Param senderUuid path string true "Sender ID"
+// @Param limit query string false "Limit, default 10"
+// @Param offset query string false "Offset, default 0"
+// @Success 200 {array} LedgerEntryOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /account/{senderUuid}/ledger [get]
+func (app *App) ListLedger(c *gin.Context) {
+	sender, ok := app.loadAccount(c)
+	if !ok {
+		return
+	}
+	limit, offset, ok := parseLimitOffset(c)
+	if !ok {
+		return
+	}
+	retdata, err := (&data.LedgerEntry{}).List(app.db, sender.SenderUuid, limit, offset)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list ledger: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list ledger due to internal server error"})
+		return
+	}
+	res := make([]*LedgerEntryOut, 0, len(retdata))
+	for _, entry := range retdata {
+		res = append(res, (&LedgerEntryOut{}).FromModel(entry))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// AddPrice godoc
+// @Summary Add per-segment price for a country or a number prefix
+// @Produce json
+// @Param price body PriceIn true "New price"
+// @Success 201 {object} PriceOut
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /price [post]
+func (app *App) AddPrice(c *gin.Context) {
+	var req PriceIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	price, err := req.ToModel()
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad price: " + err.Error()})
+		return
+	}
+	if err := price.Save(app.db); err != nil {
+		c.Error(fmt.Errorf("can't save price to database: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't save price due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusCreated, (&PriceOut{}).FromModel(price, app.cfg.Currency))
+}
+
+// GetPrice godoc
+// @Summary Get price
+// @Produce json
+// @Param priceUuid path string true "Price ID"
+// @Success 200 {object} PriceOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /price/{priceUuid} [get]
+func (app *App) GetPrice(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("priceUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse price uuid"})
+		return
+	}
+	price := &data.Price{}
+	if err := price.LoadById(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't load price: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested price"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load price due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, (&PriceOut{}).FromModel(price, app.cfg.Currency))
+}
+
+// DeletePrice godoc
+// @Summary Delete price
+// @Param priceUuid path string true "Price ID"
+// @Success 204
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /price/{priceUuid} [delete]
+func (app *App) DeletePrice(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("priceUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse price uuid"})
+		return
+	}
+	if err := (&data.Price{}).Delete(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't delete price: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested price"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't delete price due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// ListPrices godoc
+// @Summary List prices
+// @Produce json
+// @Success 200 {array} PriceOut
+// @Failure 500 {object} ErrorMessage
+// @Router /price [get]
+func (app *App) ListPrices(c *gin.Context) {
+	retdata, err := (&data.Price{}).List(app.db)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list prices: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list prices due to internal server error"})
+		return
+	}
+	res := make([]*PriceOut, 0, len(retdata))
+	for _, price := range retdata {
+		res = append(res, (&PriceOut{}).FromModel(price, app.cfg.Currency))
+	}
+	c.JSON(http.StatusOK, res)
+}
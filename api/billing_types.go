@@ -0,0 +1,104 @@
+package api
+
+import (
+	"github.com/google/uuid"
+	"smsgate-mock/data"
+	"smsgate-mock/smsc"
+	"time"
+)
+
+// CodeInsufficientBalance is returned with 402 when a prepaid sender can't pay for a message
+const CodeInsufficientBalance = "INSUFFICIENT_BALANCE"
+
+type BalanceErrorMessage struct {
+	Error   string  `json:"error"`
+	Code    string  `json:"code"`
+	Balance float64 `json:"balance"`
+	Price   float64 `json:"price"`
+}
+
+func (s *BalanceErrorMessage) FromModel(src *smsc.BalanceError) *BalanceErrorMessage {
+	s.Error = src.Error()
+	s.Code = CodeInsufficientBalance
+	s.Balance = src.Balance
+	s.Price = src.Price
+	return s
+}
+
+type PriceIn struct {
+	// Country is ISO 3166-1 alpha-2 code, Prefix is international number prefix, the longest matching prefix
+	// wins over the country
+	Country    string  `json:"country,omitempty"`
+	Prefix     string  `json:"prefix,omitempty"`
+	PerSegment float64 `json:"perSegment"`
+}
+
+func (s *PriceIn) ToModel() (*data.Price, error) {
+	price := &data.Price{Country: s.Country, Prefix: s.Prefix, PerSegment: s.PerSegment}
+	return price, price.Validate()
+}
+
+type PriceOut struct {
+	PriceUuid uuid.UUID `json:"priceUuid"`
+	PriceIn
+	Currency string    `json:"currency"`
+	Create   time.Time `json:"created"`
+}
+
+func (s *PriceOut) FromModel(src *data.Price, currency string) *PriceOut {
+	s.PriceUuid = src.PriceUuid
+	s.Country = src.Country
+	s.Prefix = src.Prefix
+	s.PerSegment = src.PerSegment
+	s.Currency = currency
+	s.Create = src.Create
+	return s
+}
+
+type TopUpIn struct {
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description,omitempty"`
+}
+
+type AccountOut struct {
+	SenderUuid uuid.UUID `json:"senderUuid"`
+	Login      string    `json:"login"`
+	Prepaid    bool      `json:"prepaid"`
+	Balance    float64   `json:"balance"`
+	Currency   string    `json:"currency"`
+}
+
+func (s *AccountOut) FromModel(src *data.Sender, currency string) *AccountOut {
+	s.SenderUuid = src.SenderUuid
+	s.Login = src.Login
+	s.Prepaid = src.IsPrepaid()
+	s.Balance = src.Balance
+	s.Currency = currency
+	return s
+}
+
+type LedgerEntryOut struct {
+	EntryUuid   uuid.UUID  `json:"entryUuid"`
+	MessageUuid *uuid.UUID `json:"messageUuid,omitempty"`
+	// Type is DEBIT, REFUND or TOPUP
+	Type string `json:"type"`
+	// Amount is negative for debits, Balance is the sender's balance after the entry
+	Amount      float64   `json:"amount"`
+	Balance     float64   `json:"balance"`
+	Description string    `json:"description,omitempty"`
+	Create      time.Time `json:"created"`
+}
+
+func (s *LedgerEntryOut) FromModel(src *data.LedgerEntry) *LedgerEntryOut {
+	s.EntryUuid = src.EntryUuid
+	if src.MessageUuid != uuid.Nil {
+		id := src.MessageUuid
+		s.MessageUuid = &id
+	}
+	s.Type = src.Type
+	s.Amount = src.Amount
+	s.Balance = src.Balance
+	s.Description = src.Description
+	s.Create = src.Create
+	return s
+}
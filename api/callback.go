@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"smsgate-mock/data"
+)
+
+// sendDeliveryReport posts status of the message to its callback URL or to the sender's default one
+func (app *App) sendDeliveryReport(msg *data.Message) {
+	switch msg.Dialect {
+	case TwilioDialect:
+		app.sendTwilioStatusCallback(msg)
+		return
+	case KannelDialect:
+		app.sendKannelDlr(msg)
+		return
+	}
+	url := msg.CallbackUrl
+	if len(url) == 0 {
+		sender := &data.Sender{}
+		if err := sender.LoadById(app.db, msg.SenderUuid); err != nil {
+			log.Printf("Can't load sender for delivery report of %s: %v", msg.MessageUuid, err)
+			return
+		}
+		url = sender.CallbackUrl
+	}
+	if len(url) == 0 {
+		return
+	}
+	if d, ok := app.dialects[msg.Dialect]; ok {
+		app.sendDialectCallback(d, msg, url)
+		return
+	}
+	body, _ := json.Marshal((&DeliveryReportOut{}).FromModel(msg))
+	hook := &data.Webhook{
+		SenderUuid:  msg.SenderUuid,
+		Url:         url,
+		ContentType: "application/json",
+		Body:        string(body),
+	}
+	if err := app.webhooks.Enqueue(hook); err != nil {
+		log.Printf("Can't enqueue delivery report of %s to %s: %v", msg.MessageUuid, url, err)
+	}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"smsgate-mock/data"
+	"strings"
+)
+
+// campaignOut adds the progress to the campaign, writes error response if it fails
+func (app *App) campaignOut(c *gin.Context, campaign *data.Campaign) (*CampaignOut, bool) {
+	progress, err := campaign.Progress(app.db)
+	if err != nil {
+		c.Error(fmt.Errorf("can't count campaign progress: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load campaign due to internal server error"})
+		return nil, false
+	}
+	return (&CampaignOut{}).FromModel(campaign, progress), true
+}
+
+// AddCampaign godoc
+// @Summary Create campaign, it's sent in the background at its speed
+// @Produce json
+// @Param campaign body CampaignIn true "Campaign data"
+// @Success 201 {object} CampaignOut
+// @Failure 401 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /campaign [post]
+func (app *App) AddCampaign(c *gin.Context) {
+	var req CampaignIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	sender := &data.Sender{}
+	if err := sender.LoadByLogin(app.db, req.Login); err != nil {
+		c.Error(fmt.Errorf("can't load sender: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find sender"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't create campaign due to internal server error"})
+		}
+		return
+	}
+	if sender.Password != req.Password {
+		c.JSON(http.StatusUnauthorized, &ErrorMessage{"Password mismatch"})
+		return
+	}
+	campaign := req.ToModel(sender.SenderUuid)
+	if err := campaign.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad campaign: " + err.Error()})
+		return
+	}
+	if err := campaign.Save(app.db); err != nil {
+		c.Error(fmt.Errorf("can't save campaign: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't create campaign due to internal server error"})
+		return
+	}
+	if res, ok := app.campaignOut(c, campaign); ok {
+		c.JSON(http.StatusCreated, res)
+	}
+}
+
+// ListCampaigns godoc
+// @Summary List campaigns, newest first
+// @Produce json
+// @Param status query string false "RUNNING, PAUSED, ABORTED or COMPLETED"
+// @Success 200 {array} CampaignOut
+// @Failure 500 {object} ErrorMessage
+// @Router /campaign [get]
+func (app *App) ListCampaigns(c *gin.Context) {
+	campaigns, err := (&data.Campaign{}).List(app.db, strings.ToUpper(c.Query("status")))
+	if err != nil {
+		c.Error(fmt.Errorf("can't list campaigns: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list campaigns due to internal server error"})
+		return
+	}
+	res := make([]*CampaignOut, len(campaigns))
+	for i, campaign := range campaigns {
+		var ok bool
+		if res[i], ok = app.campaignOut(c, campaign); !ok {
+			return
+		}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetCampaign godoc
+// @Summary Get campaign with its progress
+// @Produce json
+// @Param campaignUuid path string true "Campaign ID"
+// @Success 200 {object} CampaignOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /campaign/{campaignUuid} [get]
+func (app *App) GetCampaign(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("campaignUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse campaign uuid"})
+		return
+	}
+	campaign := &data.Campaign{}
+	if err := campaign.LoadById(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't load campaign: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested campaign"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load campaign due to internal server error"})
+		}
+		return
+	}
+	if res, ok := app.campaignOut(c, campaign); ok {
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// changeCampaign applies the change to the campaign from the path and writes the response
+func (app *App) changeCampaign(c *gin.Context, change func(id uuid.UUID) (*data.Campaign, error)) {
+	id, err := uuid.Parse(c.Param("campaignUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse campaign uuid"})
+		return
+	}
+	campaign, err := change(id)
+	if err != nil {
+		c.Error(fmt.Errorf("can't change campaign: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested campaign"})
+		} else if strings.Contains(err.Error(), "can't be") {
+			c.JSON(http.StatusConflict, &ErrorMessage{"Can't change campaign: " + err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't change campaign due to internal server error"})
+		}
+		return
+	}
+	if res, ok := app.campaignOut(c, campaign); ok {
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// PauseCampaign godoc
+// @Summary Pause running campaign
+// @Produce json
+// @Param campaignUuid path string true "Campaign ID"
+// @Success 200 {object} CampaignOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 409 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /campaign/{campaignUuid}/pause [post]
+func (app *App) PauseCampaign(c *gin.Context) {
+	app.changeCampaign(c, app.smsc.PauseCampaign)
+}
+
+// ResumeCampaign godoc
+// @Summary Resume paused campaign
+// @Produce json
+// @Param campaignUuid path string true "Campaign ID"
+// @Success 200 {object} CampaignOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 409 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /campaign/{campaignUuid}/resume [post]
+func (app *App) ResumeCampaign(c *gin.Context) {
+	app.changeCampaign(c, app.smsc.ResumeCampaign)
+}
+
+// AbortCampaign godoc
+// @Summary Abort campaign canceling its messages which haven't gone out yet
+// @Produce json
+// @Param campaignUuid path string true "Campaign ID"
+// @Success 200 {object} CampaignOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 409 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /campaign/{campaignUuid}/abort [post]
+func (app *App) AbortCampaign(c *gin.Context) {
+	app.changeCampaign(c, app.smsc.AbortCampaign)
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"github.com/google/uuid"
+	"smsgate-mock/data"
+	"time"
+)
+
+type CampaignRecipientIn struct {
+	PhoneNumber string `json:"phoneNumber"`
+	// Values are used in the template like {{.name}}
+	Values map[string]string `json:"values,omitempty"`
+}
+
+type CampaignIn struct {
+	Login       string `json:"login"`
+	Password    string `json:"password"`
+	Name        string `json:"name"`
+	SenderName  string `json:"senderName"`
+	MessageType string `json:"messageType"`
+	// Template is a Go text/template of the message text with the recipient's values and {{.phoneNumber}}
+	Template string `json:"template"`
+	// Speed is messages per second, zero sends as fast as possible
+	Speed      float64               `json:"speed,omitempty"`
+	Recipients []CampaignRecipientIn `json:"recipients"`
+}
+
+func (s *CampaignIn) ToModel(senderUuid uuid.UUID) *data.Campaign {
+	campaign := &data.Campaign{
+		SenderUuid:  senderUuid,
+		Name:        s.Name,
+		SenderName:  s.SenderName,
+		MessageType: s.MessageType,
+		Template:    s.Template,
+		Speed:       s.Speed,
+		Recipients:  make([]data.CampaignRecipient, len(s.Recipients)),
+	}
+	for i, recipient := range s.Recipients {
+		campaign.Recipients[i] = data.CampaignRecipient{PhoneNumber: recipient.PhoneNumber, Values: recipient.Values}
+	}
+	return campaign
+}
+
+type CampaignProgressOut struct {
+	// Pending recipients have no message yet, Skipped ones never get it as the campaign was aborted;
+	// Failed includes rejected recipients
+	Pending   int            `json:"pending"`
+	Skipped   int            `json:"skipped"`
+	Queued    int            `json:"queued"`
+	Sent      int            `json:"sent"`
+	Delivered int            `json:"delivered"`
+	Failed    int            `json:"failed"`
+	Statuses  map[string]int `json:"statuses"`
+}
+
+func (s *CampaignProgressOut) FromModel(src *data.CampaignProgress) *CampaignProgressOut {
+	s.Pending = src.Pending
+	s.Skipped = src.Skipped
+	s.Queued = src.Queued
+	s.Sent = src.Sent
+	s.Delivered = src.Delivered
+	s.Failed = src.Failed
+	s.Statuses = src.Statuses
+	return s
+}
+
+type CampaignOut struct {
+	CampaignUuid uuid.UUID `json:"campaignUuid"`
+	SenderUuid   uuid.UUID `json:"senderUuid"`
+	Name         string    `json:"name"`
+	SenderName   string    `json:"senderName"`
+	MessageType  string    `json:"messageType"`
+	Template     string    `json:"template"`
+	Speed        float64   `json:"speed"`
+	// Status is RUNNING, PAUSED, ABORTED or COMPLETED
+	Status     string               `json:"status"`
+	Recipients int                  `json:"recipients"`
+	Progress   *CampaignProgressOut `json:"progress"`
+	// LastError is the reason the last recipient was rejected
+	LastError string    `json:"lastError,omitempty"`
+	Create    time.Time `json:"created"`
+	Updated   time.Time `json:"updated"`
+	Done      time.Time `json:"done"`
+}
+
+func (s *CampaignOut) FromModel(src *data.Campaign, progress *data.CampaignProgress) *CampaignOut {
+	s.CampaignUuid = src.CampaignUuid
+	s.SenderUuid = src.SenderUuid
+	s.Name = src.Name
+	s.SenderName = src.SenderName
+	s.MessageType = src.MessageType
+	s.Template = src.Template
+	s.Speed = src.Speed
+	s.Status = src.Status
+	s.Recipients = src.Total
+	s.Progress = (&CampaignProgressOut{}).FromModel(progress)
+	s.LastError = src.LastError
+	s.Create = src.Create
+	s.Updated = src.Updated
+	s.Done = src.Done
+	return s
+}
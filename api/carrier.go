@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"smsgate-mock/data"
+	"smsgate-mock/phone"
+	"strings"
+)
+
+// ListCarriers godoc
+// @Summary List carriers of the prefix table with their profiles
+// @Produce json
+// @Success 200 {array} CarrierOut
+// @Failure 500 {object} ErrorMessage
+// @Router /carrier [get]
+func (app *App) ListCarriers(c *gin.Context) {
+	profiles, err := (&data.CarrierProfile{}).List(app.db)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list carrier profiles: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list carriers due to internal server error"})
+		return
+	}
+	res := make([]*CarrierOut, 0)
+	seen := make(map[string]bool)
+	for i := range phone.Carriers {
+		carrier := &phone.Carriers[i]
+		if seen[carrier.Id()] {
+			continue
+		}
+		seen[carrier.Id()] = true
+		out := (&CarrierOut{}).FromModel(carrier)
+		if profile, ok := profiles[carrier.Id()]; ok {
+			out.Profile = (&CarrierProfileOut{}).FromModel(profile)
+		}
+		res = append(res, out)
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetCarrier godoc
+// @Summary Get carrier with its profile
+// @Produce json
+// @Param carrierId path string true "MCC and MNC"
+// @Success 200 {object} CarrierOut
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /carrier/{carrierId} [get]
+func (app *App) GetCarrier(c *gin.Context) {
+	carrier := phone.CarrierById(c.Param("carrierId"))
+	if carrier == nil {
+		c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested carrier"})
+		return
+	}
+	out := (&CarrierOut{}).FromModel(carrier)
+	profile := &data.CarrierProfile{}
+	found, err := profile.Load(app.db, carrier.Id())
+	if err != nil {
+		c.Error(fmt.Errorf("can't load carrier profile: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load carrier due to internal server error"})
+		return
+	}
+	if found {
+		out.Profile = (&CarrierProfileOut{}).FromModel(profile)
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// SetCarrierProfile godoc
+// @Summary Set latency, failure rate and outage windows of carrier
+// @Produce json
+// @Param carrierId path string true "MCC and MNC"
+// @Param profile body CarrierProfileIn true "Carrier behaviour"
+// @Success 200 {object} CarrierProfileOut
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /carrier/{carrierId}/profile [put]
+func (app *App) SetCarrierProfile(c *gin.Context) {
+	carrier := phone.CarrierById(c.Param("carrierId"))
+	if carrier == nil {
+		c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested carrier"})
+		return
+	}
+	var req CarrierProfileIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	profile, err := req.ToModel(carrier.Id())
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad carrier profile: " + err.Error()})
+		return
+	}
+	if err := profile.Save(app.db); err != nil {
+		c.Error(fmt.Errorf("can't save carrier profile: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't save carrier profile due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, (&CarrierProfileOut{}).FromModel(profile))
+}
+
+// DeleteCarrierProfile godoc
+// @Summary Restore normal behaviour of carrier
+// @Param carrierId path string true "MCC and MNC"
+// @Success 204
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /carrier/{carrierId}/profile [delete]
+func (app *App) DeleteCarrierProfile(c *gin.Context) {
+	if err := (&data.CarrierProfile{}).Delete(app.db, c.Param("carrierId")); err != nil {
+		c.Error(fmt.Errorf("can't delete carrier profile: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested carrier profile"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't delete carrier profile due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{})
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"fmt"
+	"smsgate-mock/data"
+	"smsgate-mock/phone"
+	"time"
+)
+
+type OutageInOut struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type CarrierProfileIn struct {
+	// Latency like "5s" is added to the delivery of every message
+	Latency string `json:"latency,omitempty"`
+	// FailureRate is a percentage of messages which become UNDELIVERED with ErrorCode and ErrorMessage
+	// (34, system failure by default), messages submitted during Outages fail the same way
+	FailureRate  float64       `json:"failureRate,omitempty"`
+	ErrorCode    int           `json:"errorCode,omitempty"`
+	ErrorMessage string        `json:"errorMessage,omitempty"`
+	Outages      []OutageInOut `json:"outages,omitempty"`
+}
+
+func (s *CarrierProfileIn) ToModel(carrierId string) (*data.CarrierProfile, error) {
+	profile := &data.CarrierProfile{
+		CarrierId:    carrierId,
+		FailureRate:  s.FailureRate,
+		ErrorCode:    s.ErrorCode,
+		ErrorMessage: s.ErrorMessage,
+	}
+	if len(s.Latency) > 0 {
+		latency, err := time.ParseDuration(s.Latency)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse latency: %v", err)
+		}
+		profile.Latency = latency
+	}
+	for _, outage := range s.Outages {
+		profile.Outages = append(profile.Outages, data.Outage{Start: outage.Start, End: outage.End})
+	}
+	return profile, profile.Validate()
+}
+
+type CarrierProfileOut struct {
+	CarrierProfileIn
+	Updated time.Time `json:"updated"`
+}
+
+func (s *CarrierProfileOut) FromModel(src *data.CarrierProfile) *CarrierProfileOut {
+	if src.Latency > 0 {
+		s.Latency = src.Latency.String()
+	}
+	s.FailureRate = src.FailureRate
+	s.ErrorCode = src.ErrorCode
+	s.ErrorMessage = src.ErrorMessage
+	for _, outage := range src.Outages {
+		s.Outages = append(s.Outages, OutageInOut{Start: outage.Start, End: outage.End})
+	}
+	s.Updated = src.Updated
+	return s
+}
+
+type CarrierOut struct {
+	// CarrierId is MCC and MNC
+	CarrierId string   `json:"carrierId"`
+	Mcc       string   `json:"mcc"`
+	Mnc       string   `json:"mnc"`
+	Name      string   `json:"name"`
+	Country   string   `json:"country"`
+	Prefixes  []string `json:"prefixes"`
+	// Profile is set if the carrier's behaviour is changed
+	Profile *CarrierProfileOut `json:"profile,omitempty"`
+}
+
+func (s *CarrierOut) FromModel(src *phone.Carrier) *CarrierOut {
+	s.CarrierId = src.Id()
+	s.Mcc = src.Mcc
+	s.Mnc = src.Mnc
+	s.Name = src.Name
+	s.Country = src.Country
+	s.Prefixes = make([]string, 0)
+	for _, carrier := range phone.Carriers {
+		if carrier.Id() == s.CarrierId {
+			s.Prefixes = append(s.Prefixes, carrier.Prefix)
+		}
+	}
+	return s
+}
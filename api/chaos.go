@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net"
+	"net/http"
+	"smsgate-mock/data"
+	"time"
+)
+
+type connKey struct{}
+
+// withConn keeps the connection in the request context, so chaos can break it
+func withConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+// requestConn returns the connection of the request, nil if it isn't served by App.Serve
+func requestConn(c *gin.Context) net.Conn {
+	conn, _ := c.Request.Context().Value(connKey{}).(net.Conn)
+	return conn
+}
+
+// chaos hijacks the connection and sends the response broken as the profile says
+func (app *App) chaos(c *gin.Context, profile *data.FaultProfile, body []byte) {
+	status := c.Writer.Status()
+	conn, rw, err := c.Writer.Hijack()
+	if err != nil {
+		log.Printf("Can't hijack connection for chaos %s: %v", profile.Chaos, err)
+		return
+	}
+	defer conn.Close()
+	writeHead(rw.Writer, status, c.Writer.Header(), len(body))
+	switch profile.Chaos {
+	case data.ChaosReset:
+		rw.Write(body[:len(body)/2])
+		rw.Flush()
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			// zero linger makes Close send RST instead of FIN
+			tcp.SetLinger(0)
+		}
+	case data.ChaosTruncate:
+		rw.Write(body[:len(body)/2])
+		rw.Flush()
+	case data.ChaosSlow:
+		if err := rw.Flush(); err != nil {
+			return
+		}
+		chunk, interval := profile.DripBytes, profile.DripInterval
+		if chunk <= 0 {
+			chunk = 1
+		}
+		if interval <= 0 {
+			interval = 100 * time.Millisecond
+		}
+		for len(body) > 0 {
+			time.Sleep(interval)
+			n := chunk
+			if n > len(body) {
+				n = len(body)
+			}
+			if _, err := conn.Write(body[:n]); err != nil {
+				return
+			}
+			body = body[n:]
+		}
+	}
+}
+
+// writeHead writes status line and headers of HTTP/1.1 response, the connection is closed after it
+func writeHead(w *bufio.Writer, status int, header http.Header, length int) {
+	fmt.Fprintf(w, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+	header.Set("Content-Length", fmt.Sprint(length))
+	header.Set("Connection", "close")
+	header.Write(w)
+	w.WriteString("\r\n")
+}
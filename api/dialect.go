@@ -0,0 +1,257 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+	"smsgate-mock/data"
+	"smsgate-mock/dialect"
+	"smsgate-mock/smpp"
+	"strconv"
+	"strings"
+)
+
+// builtinDialects can't be redefined by dialect files
+var builtinDialects = map[string]bool{TwilioDialect: true, KannelDialect: true, smpp.Dialect: true}
+
+// loadDialects reads dialect files from DIALECTS_DIR
+func (app *App) loadDialects() {
+	app.dialects = make(map[string]*dialect.Dialect)
+	if len(app.cfg.DialectsDir) == 0 {
+		return
+	}
+	dialects, err := dialect.LoadDir(app.cfg.DialectsDir)
+	if err != nil {
+		log.Fatalf("Can't load dialects from %s: %v", app.cfg.DialectsDir, err)
+	}
+	for _, d := range dialects {
+		if builtinDialects[d.Name] {
+			log.Fatalf("Dialect %s is built in and can't be redefined", d.Name)
+		}
+		app.dialects[d.Name] = d
+	}
+}
+
+// mountDialects adds routes of every loaded dialect
+func (app *App) mountDialects() {
+	for _, d := range app.dialects {
+		r := app.r.Group(d.BasePath)
+		r.Handle(d.Send.Method, d.Send.Path, app.dialectSend(d))
+		if d.Status != nil {
+			r.Handle(d.Status.Method, d.Status.Path, app.dialectStatus(d))
+		}
+		log.Printf("Dialect %s is mounted at %s", d.Name, d.BasePath)
+	}
+}
+
+// dialectFields reads request fields in the endpoint's format, path parameters included
+func dialectFields(c *gin.Context, e *dialect.Endpoint) (map[string]string, error) {
+	fields := make(map[string]string)
+	switch e.Format {
+	case dialect.FormatJson:
+		if c.Request.ContentLength != 0 {
+			body := make(map[string]interface{})
+			decoder := json.NewDecoder(c.Request.Body)
+			decoder.UseNumber()
+			if err := decoder.Decode(&body); err != nil {
+				return nil, fmt.Errorf("can't parse json: %v", err)
+			}
+			flatten("", body, fields)
+		}
+	case dialect.FormatForm:
+		if err := c.Request.ParseForm(); err != nil {
+			return nil, fmt.Errorf("can't parse form: %v", err)
+		}
+		for k := range c.Request.Form {
+			fields[k] = c.Request.Form.Get(k)
+		}
+	case dialect.FormatQuery:
+		for k, v := range c.Request.URL.Query() {
+			fields[k] = v[0]
+		}
+	}
+	for _, p := range c.Params {
+		fields[p.Key] = p.Value
+	}
+	return fields, nil
+}
+
+// flatten makes dotted names for nested JSON objects
+func flatten(prefix string, src map[string]interface{}, dst map[string]string) {
+	for k, v := range src {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			flatten(prefix+k+".", value, dst)
+		case nil:
+		default:
+			dst[prefix+k] = fmt.Sprint(value)
+		}
+	}
+}
+
+// dialectAuth finds the sender by credentials in the dialect's auth style
+func (app *App) dialectAuth(c *gin.Context, d *dialect.Dialect, fields map[string]string) (*data.Sender, error) {
+	var login, password string
+	switch d.Auth.Type {
+	case dialect.AuthBasic:
+		login, password, _ = c.Request.BasicAuth()
+	case dialect.AuthFields:
+		login, password = fields[d.Auth.Login], fields[d.Auth.Password]
+	case dialect.AuthHeader:
+		login, password = c.GetHeader(d.Auth.Login), c.GetHeader(d.Auth.Password)
+	case dialect.AuthNone:
+		login = d.Auth.Login
+	}
+	sender := &data.Sender{}
+	if err := sender.LoadByLogin(app.db, login); err != nil {
+		return nil, err
+	}
+	if d.Auth.Type != dialect.AuthNone && sender.Password != password {
+		return nil, fmt.Errorf("wrong password")
+	}
+	return sender, nil
+}
+
+func (app *App) dialectRespond(c *gin.Context, resp *dialect.Response, view *dialect.View) {
+	body, err := resp.Render(view)
+	if err != nil {
+		c.Error(fmt.Errorf("can't render response: %v", err))
+		c.String(http.StatusInternalServerError, "can't render response")
+		return
+	}
+	c.Data(resp.Status, resp.ContentType, body)
+}
+
+func (app *App) dialectError(c *gin.Context, d *dialect.Dialect, kind, field string, err error) {
+	c.Error(err)
+	app.dialectRespond(c, d.Errors[kind], d.ErrorView(field, err))
+}
+
+func (app *App) dialectSend(d *dialect.Dialect) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fields, err := dialectFields(c, &d.Send)
+		if err != nil {
+			app.dialectError(c, d, dialect.ErrorValidation, "", err)
+			return
+		}
+		sender, err := app.dialectAuth(c, d, fields)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "wrong password") {
+				app.dialectError(c, d, dialect.ErrorAuth, "", fmt.Errorf("authorization failed"))
+			} else {
+				app.dialectError(c, d, dialect.ErrorInternal, "", err)
+			}
+			return
+		}
+		names := d.Send.Fields
+		msg := &data.Message{
+			Sender:      sender,
+			SenderName:  fields[names[dialect.FieldSender]],
+			MessageType: "TEXT",
+			MessageText: fields[names[dialect.FieldText]],
+			PhoneNumber: fields[names[dialect.FieldPhone]],
+			CallbackUrl: fields[names[dialect.FieldCallback]],
+			Dialect:     d.Name,
+		}
+		if len(msg.PhoneNumber) == 0 {
+			app.dialectError(c, d, dialect.ErrorValidation, names[dialect.FieldPhone], fmt.Errorf("%s is required", names[dialect.FieldPhone]))
+			return
+		}
+		if len(msg.MessageText) == 0 {
+			app.dialectError(c, d, dialect.ErrorValidation, names[dialect.FieldText], fmt.Errorf("%s is required", names[dialect.FieldText]))
+			return
+		}
+		if expiration := fields[names[dialect.FieldExpiration]]; len(expiration) > 0 {
+			msg.ExpirationTimeout, err = strconv.Atoi(expiration)
+			if err != nil || msg.ExpirationTimeout < 0 {
+				app.dialectError(c, d, dialect.ErrorValidation, names[dialect.FieldExpiration], fmt.Errorf("%s must be a number of seconds", names[dialect.FieldExpiration]))
+				return
+			}
+		}
+		if err := app.smsc.Submit(msg); err != nil {
+			if _, ok := tooLongError(err); ok {
+				app.dialectError(c, d, dialect.ErrorValidation, names[dialect.FieldText], err)
+				return
+			}
+			if _, ok := senderNameError(err); ok {
+				app.dialectError(c, d, dialect.ErrorValidation, names[dialect.FieldSender], err)
+				return
+			}
+			if _, ok := balanceError(err); ok {
+				app.dialectError(c, d, dialect.ErrorPayment, "", err)
+				return
+			}
+			if _, ok := limitError(c, err); ok {
+				app.dialectError(c, d, dialect.ErrorThrottled, "", err)
+				return
+			}
+			app.dialectError(c, d, dialect.ErrorInternal, "", fmt.Errorf("can't save message: %v", err))
+			return
+		}
+		app.dialectRespond(c, &d.Send.Response, d.View(msg))
+	}
+}
+
+func (app *App) dialectStatus(d *dialect.Dialect) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fields, err := dialectFields(c, d.Status)
+		if err != nil {
+			app.dialectError(c, d, dialect.ErrorValidation, "", err)
+			return
+		}
+		sender, err := app.dialectAuth(c, d, fields)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "wrong password") {
+				app.dialectError(c, d, dialect.ErrorAuth, "", fmt.Errorf("authorization failed"))
+			} else {
+				app.dialectError(c, d, dialect.ErrorInternal, "", err)
+			}
+			return
+		}
+		idField := d.Status.Fields[dialect.FieldId]
+		id, err := uuid.Parse(fields[idField])
+		if err != nil {
+			app.dialectError(c, d, dialect.ErrorNotFound, idField, fmt.Errorf("message not found"))
+			return
+		}
+		msg := &data.Message{}
+		if err := msg.LoadById(app.db, id); err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				app.dialectError(c, d, dialect.ErrorNotFound, idField, fmt.Errorf("message not found"))
+			} else {
+				app.dialectError(c, d, dialect.ErrorInternal, "", err)
+			}
+			return
+		}
+		if msg.SenderUuid != sender.SenderUuid {
+			app.dialectError(c, d, dialect.ErrorNotFound, idField, fmt.Errorf("message not found"))
+			return
+		}
+		app.dialectRespond(c, &d.Status.Response, d.View(msg))
+	}
+}
+
+// sendDialectCallback sends the dialect's status report to the message or sender callback URL
+func (app *App) sendDialectCallback(d *dialect.Dialect, msg *data.Message, url string) {
+	if d.Callback == nil {
+		return
+	}
+	body, err := d.Callback.Render(d.View(msg))
+	if err != nil {
+		log.Printf("Can't render %s callback of %s: %v", d.Name, msg.MessageUuid, err)
+		return
+	}
+	hook := &data.Webhook{
+		SenderUuid:  msg.SenderUuid,
+		Method:      d.Callback.Method,
+		Url:         url,
+		ContentType: d.Callback.ContentType,
+		Body:        string(body),
+	}
+	if err := app.webhooks.Enqueue(hook); err != nil {
+		log.Printf("Can't enqueue %s callback of %s to %s: %v", d.Name, msg.MessageUuid, url, err)
+	}
+}
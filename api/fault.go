@@ -0,0 +1,283 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"smsgate-mock/data"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var defaultFaultStatuses = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusTooManyRequests,
+}
+
+// faultExempt tells whether the request can't be affected by faults: admin API must always work
+func faultExempt(path string) bool {
+	return strings.HasPrefix(path, "/api/v1/admin") || strings.HasPrefix(path, "/swagger")
+}
+
+// requestLogin finds sender's login in Basic auth, query or JSON body of the request
+func requestLogin(c *gin.Context) string {
+	if login, _, ok := c.Request.BasicAuth(); ok {
+		return login
+	}
+	for _, name := range []string{"login", "username"} {
+		if login := c.Query(name); len(login) > 0 {
+			return login
+		}
+	}
+	if c.Request.ContentLength > 0 && strings.Contains(c.ContentType(), "json") {
+		body, _ := ioutil.ReadAll(c.Request.Body)
+		c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+		var req struct {
+			Login string `json:"login"`
+		}
+		json.Unmarshal(body, &req)
+		return req.Login
+	}
+	return ""
+}
+
+// bufferedWriter keeps the response body to send it broken
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// FaultMiddleware injects maintenance mode and faults of the first matching profile
+func (app *App) FaultMiddleware(c *gin.Context) {
+	if faultExempt(c.Request.URL.Path) {
+		c.Next()
+		return
+	}
+	maintenance := &data.Maintenance{}
+	if err := maintenance.Load(app.db); err != nil {
+		log.Printf("Can't load maintenance mode: %v", err)
+	} else if maintenance.Enabled {
+		if maintenance.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(maintenance.RetryAfter))
+		}
+		message := maintenance.Message
+		if len(message) == 0 {
+			message = "Service is under maintenance"
+		}
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, &ErrorMessage{message})
+		return
+	}
+	profile, err := (&data.FaultProfile{}).Match(app.db, c.FullPath(), c.Request.URL.Path, c.Request.Method, requestLogin(c))
+	if err != nil {
+		log.Printf("Can't load fault profiles: %v", err)
+	}
+	if profile == nil {
+		c.Next()
+		return
+	}
+	delay := profile.Latency
+	if profile.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(profile.Jitter)))
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-c.Request.Context().Done():
+			c.Abort()
+			return
+		}
+	}
+	if rand.Float64()*100 < profile.ErrorRate {
+		statuses := profile.ErrorStatuses
+		if len(statuses) == 0 {
+			statuses = defaultFaultStatuses
+		}
+		status := statuses[rand.Intn(len(statuses))]
+		if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+			c.Header("Retry-After", "1")
+		}
+		c.AbortWithStatusJSON(status, &ErrorMessage{http.StatusText(status)})
+		return
+	}
+	if len(profile.Chaos) > 0 && requestConn(c) != nil && rand.Float64()*100 < profile.ChaosRate {
+		w := &bufferedWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		app.chaos(c, profile, w.body.Bytes())
+		return
+	}
+	if rand.Float64()*100 < profile.MalformedRate {
+		w := &bufferedWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		body := w.body.Bytes()
+		c.Writer.Write(body[:len(body)/2])
+		return
+	}
+	c.Next()
+}
+
+// AddFault godoc
+// @Summary Create new fault profile
+// @Produce json
+// @Param fault body FaultIn true "New fault profile"
+// @Success 201 {object} FaultOut
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /admin/fault [post]
+func (app *App) AddFault(c *gin.Context) {
+	var req FaultIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	profile, err := req.ToModel()
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad fault profile: " + err.Error()})
+		return
+	}
+	if err := profile.Save(app.db); err != nil {
+		c.Error(fmt.Errorf("can't save fault profile to database: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't save fault profile due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusCreated, (&FaultOut{}).FromModel(profile))
+}
+
+// GetFault godoc
+// @Summary Get fault profile
+// @Produce json
+// @Param faultUuid path string true "Fault profile ID"
+// @Success 200 {object} FaultOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /admin/fault/{faultUuid} [get]
+func (app *App) GetFault(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("faultUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse fault profile uuid"})
+		return
+	}
+	profile := &data.FaultProfile{}
+	if err := profile.LoadById(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't load fault profile: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested fault profile"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load fault profile due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, (&FaultOut{}).FromModel(profile))
+}
+
+// DeleteFault godoc
+// @Summary Delete fault profile
+// @Param faultUuid path string true "Fault profile ID"
+// @Success 204
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /admin/fault/{faultUuid} [delete]
+func (app *App) DeleteFault(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("faultUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse fault profile uuid"})
+		return
+	}
+	if err := (&data.FaultProfile{}).Delete(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't delete fault profile: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested fault profile"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't delete fault profile due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// ListFaults godoc
+// @Summary List fault profiles, the oldest matching one is applied to a request
+// @Produce json
+// @Success 200 {array} FaultOut
+// @Failure 500 {object} ErrorMessage
+// @Router /admin/fault [get]
+func (app *App) ListFaults(c *gin.Context) {
+	retdata, err := (&data.FaultProfile{}).List(app.db)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list fault profiles: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list fault profiles due to internal server error"})
+		return
+	}
+	res := make([]*FaultOut, len(retdata))
+	for i := 0; i < len(retdata); i++ {
+		res[i] = (&FaultOut{}).FromModel(retdata[i])
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetMaintenance godoc
+// @Summary Get maintenance mode
+// @Produce json
+// @Success 200 {object} MaintenanceInOut
+// @Failure 500 {object} ErrorMessage
+// @Router /admin/maintenance [get]
+func (app *App) GetMaintenance(c *gin.Context) {
+	maintenance := &data.Maintenance{}
+	if err := maintenance.Load(app.db); err != nil {
+		c.Error(fmt.Errorf("can't load maintenance mode: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load maintenance mode due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, (&MaintenanceInOut{}).FromModel(maintenance))
+}
+
+// SetMaintenance godoc
+// @Summary Enable or disable maintenance mode: all API requests fail with 503
+// @Produce json
+// @Param maintenance body MaintenanceInOut true "Maintenance mode"
+// @Success 200 {object} MaintenanceInOut
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /admin/maintenance [put]
+func (app *App) SetMaintenance(c *gin.Context) {
+	var req MaintenanceInOut
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	if req.RetryAfter < 0 {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Retry after can't be negative"})
+		return
+	}
+	maintenance := req.ToModel()
+	if err := maintenance.Save(app.db); err != nil {
+		c.Error(fmt.Errorf("can't save maintenance mode: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't save maintenance mode due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, (&MaintenanceInOut{}).FromModel(maintenance))
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"smsgate-mock/data"
+	"strings"
+	"time"
+)
+
+type FaultIn struct {
+	Name string `json:"name"`
+	// Route is a route pattern like /api/v1/message/:messageUuid or a request path, empty for any
+	Route  string `json:"route,omitempty"`
+	Method string `json:"method,omitempty"`
+	// Login of a sender, empty for any
+	Login string `json:"login,omitempty"`
+	// Latency and Jitter like "500ms"
+	Latency string `json:"latency,omitempty"`
+	Jitter  string `json:"jitter,omitempty"`
+	// ErrorRate is a percentage of requests failed with a random status of ErrorStatuses (500, 502, 503, 429 by default)
+	ErrorRate     float64 `json:"errorRate,omitempty"`
+	ErrorStatuses []int   `json:"errorStatuses,omitempty"`
+	// MalformedRate is a percentage of responses with the body cut in the middle
+	MalformedRate float64 `json:"malformedRate,omitempty"`
+	// Chaos is reset, truncate or slow, applied to ChaosRate percentage of responses
+	Chaos     string  `json:"chaos,omitempty"`
+	ChaosRate float64 `json:"chaosRate,omitempty"`
+	// DripBytes (1 by default) are sent every DripInterval (100ms by default) in slow mode
+	DripBytes    int    `json:"dripBytes,omitempty"`
+	DripInterval string `json:"dripInterval,omitempty"`
+}
+
+func (s *FaultIn) ToModel() (*data.FaultProfile, error) {
+	profile := &data.FaultProfile{
+		Name:          s.Name,
+		Route:         s.Route,
+		Method:        strings.ToUpper(s.Method),
+		Login:         s.Login,
+		ErrorRate:     s.ErrorRate,
+		ErrorStatuses: s.ErrorStatuses,
+		MalformedRate: s.MalformedRate,
+		Chaos:         strings.ToLower(s.Chaos),
+		ChaosRate:     s.ChaosRate,
+		DripBytes:     s.DripBytes,
+	}
+	var err error
+	if len(s.Latency) > 0 {
+		if profile.Latency, err = time.ParseDuration(s.Latency); err != nil {
+			return nil, fmt.Errorf("can't parse latency: %v", err)
+		}
+	}
+	if len(s.Jitter) > 0 {
+		if profile.Jitter, err = time.ParseDuration(s.Jitter); err != nil {
+			return nil, fmt.Errorf("can't parse jitter: %v", err)
+		}
+	}
+	if len(s.DripInterval) > 0 {
+		if profile.DripInterval, err = time.ParseDuration(s.DripInterval); err != nil {
+			return nil, fmt.Errorf("can't parse drip interval: %v", err)
+		}
+	}
+	return profile, profile.Validate()
+}
+
+type FaultOut struct {
+	FaultUuid uuid.UUID `json:"faultUuid"`
+	FaultIn
+	Created time.Time `json:"created"`
+}
+
+func (s *FaultOut) FromModel(src *data.FaultProfile) *FaultOut {
+	s.FaultUuid = src.FaultUuid
+	s.Name = src.Name
+	s.Route = src.Route
+	s.Method = src.Method
+	s.Login = src.Login
+	if src.Latency > 0 {
+		s.Latency = src.Latency.String()
+	}
+	if src.Jitter > 0 {
+		s.Jitter = src.Jitter.String()
+	}
+	s.ErrorRate = src.ErrorRate
+	s.ErrorStatuses = src.ErrorStatuses
+	s.MalformedRate = src.MalformedRate
+	s.Chaos = src.Chaos
+	s.ChaosRate = src.ChaosRate
+	s.DripBytes = src.DripBytes
+	if src.DripInterval > 0 {
+		s.DripInterval = src.DripInterval.String()
+	}
+	s.Created = src.Create
+	return s
+}
+
+type MaintenanceInOut struct {
+	Enabled bool `json:"enabled"`
+	// RetryAfter in seconds is sent in Retry-After header
+	RetryAfter int    `json:"retryAfter"`
+	Message    string `json:"message,omitempty"`
+}
+
+func (s *MaintenanceInOut) ToModel() *data.Maintenance {
+	return &data.Maintenance{Enabled: s.Enabled, RetryAfter: s.RetryAfter, Message: s.Message}
+}
+
+func (s *MaintenanceInOut) FromModel(src *data.Maintenance) *MaintenanceInOut {
+	s.Enabled = src.Enabled
+	s.RetryAfter = src.RetryAfter
+	s.Message = src.Message
+	return s
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"smsgate-mock/data"
+	"strings"
+)
+
+// handsetOut adds the number of held messages to the handset, writes error response if it fails
+func (app *App) handsetOut(c *gin.Context, handset *data.Handset) (*HandsetOut, bool) {
+	held, err := handset.Held(app.db, handset.PhoneNumber)
+	if err != nil {
+		c.Error(fmt.Errorf("can't count held messages: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load handset due to internal server error"})
+		return nil, false
+	}
+	return (&HandsetOut{}).FromModel(handset, held), true
+}
+
+// ListHandsets godoc
+// @Summary List virtual handsets
+// @Produce json
+// @Param limit query string false "Limit, default 10"
+// @Param offset query string false "Offset, default 0"
+// @Success 200 {array} HandsetOut
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /handset [get]
+func (app *App) ListHandsets(c *gin.Context) {
+	limit, offset, ok := parseLimitOffset(c)
+	if !ok {
+		return
+	}
+	handsets, err := (&data.Handset{}).List(app.db, limit, offset)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list handsets: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list handsets due to internal server error"})
+		return
+	}
+	res := make([]*HandsetOut, len(handsets))
+	for i, handset := range handsets {
+		if res[i], ok = app.handsetOut(c, handset); !ok {
+			return
+		}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// SetHandset godoc
+// @Summary Register virtual handset or change its state, messages held for it are delivered when it's back online
+// @Produce json
+// @Param phoneNumber path string true "Phone number"
+// @Param handset body HandsetIn true "Handset"
+// @Success 200 {object} HandsetOut
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /handset/{phoneNumber} [put]
+func (app *App) SetHandset(c *gin.Context) {
+	e164, ok := app.phoneNumberParam(c)
+	if !ok {
+		return
+	}
+	var req HandsetIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	handset := req.ToModel(e164)
+	if err := handset.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad handset: " + err.Error()})
+		return
+	}
+	if err := handset.Save(app.db); err != nil {
+		c.Error(fmt.Errorf("can't save handset: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't save handset due to internal server error"})
+		return
+	}
+	if res, ok := app.handsetOut(c, handset); ok {
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// GetHandset godoc
+// @Summary Get virtual handset
+// @Produce json
+// @Param phoneNumber path string true "Phone number"
+// @Success 200 {object} HandsetOut
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /handset/{phoneNumber} [get]
+func (app *App) GetHandset(c *gin.Context) {
+	e164, ok := app.phoneNumberParam(c)
+	if !ok {
+		return
+	}
+	handset := &data.Handset{}
+	found, err := handset.Load(app.db, e164)
+	if err != nil {
+		c.Error(fmt.Errorf("can't load handset: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load handset due to internal server error"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested handset"})
+		return
+	}
+	if res, ok := app.handsetOut(c, handset); ok {
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// DeleteHandset godoc
+// @Summary Delete virtual handset, messages held for it are delivered
+// @Param phoneNumber path string true "Phone number"
+// @Success 204
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /handset/{phoneNumber} [delete]
+func (app *App) DeleteHandset(c *gin.Context) {
+	e164, ok := app.phoneNumberParam(c)
+	if !ok {
+		return
+	}
+	if err := (&data.Handset{}).Delete(app.db, e164); err != nil {
+		c.Error(fmt.Errorf("can't delete handset: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested handset"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't delete handset due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{})
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"smsgate-mock/data"
+	"time"
+)
+
+type HandsetIn struct {
+	// State is ONLINE (default), OFFLINE, SWITCHED_OFF, MEMORY_FULL or ROAMING
+	State string `json:"state,omitempty"`
+}
+
+func (s *HandsetIn) ToModel(phoneNumber string) *data.Handset {
+	return &data.Handset{
+		PhoneNumber: phoneNumber,
+		State:       s.State,
+	}
+}
+
+type HandsetOut struct {
+	PhoneNumber string `json:"phoneNumber"`
+	HandsetIn
+	// HeldMessages are waiting for the handset to become available
+	HeldMessages int       `json:"heldMessages"`
+	Create       time.Time `json:"created"`
+	Updated      time.Time `json:"updated"`
+}
+
+func (s *HandsetOut) FromModel(src *data.Handset, held int) *HandsetOut {
+	s.PhoneNumber = src.PhoneNumber
+	s.State = src.State
+	s.HeldMessages = held
+	s.Create = src.Create
+	s.Updated = src.Updated
+	return s
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+	"smsgate-mock/data"
+	"strings"
+)
+
+// Inbound godoc
+// @Summary Inject inbound SMS from a handset
+// @Param message body InboundIn true "Inbound message"
+// @Success 201 {object} InboundOut
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /inbound [post]
+func (app *App) Inbound(c *gin.Context) {
+	var req InboundIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	if len(req.From) == 0 || len(req.To) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"From and to are required"})
+		return
+	}
+	in := req.ToModel()
+	if err := app.resolveInboundSender(in); err != nil {
+		c.Error(fmt.Errorf("can't find sender of inbound message: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find sender"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't save message due to internal server error"})
+		}
+		return
+	}
+	if err := in.Save(app.db); err != nil {
+		c.Error(fmt.Errorf("can't save inbound message: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't save message due to internal server error"})
+		return
+	}
+	app.forwardInbound(in)
+	c.JSON(http.StatusCreated, (&InboundOut{}).FromModel(in))
+}
+
+// resolveInboundSender checks the given sender or takes the sender of the latest message to the handset,
+// preferring messages with sender name equal to the inbound recipient
+func (app *App) resolveInboundSender(in *data.Inbound) error {
+	if in.SenderUuid != uuid.Nil {
+		return (&data.Sender{}).LoadById(app.db, in.SenderUuid)
+	}
+	messages, err := (&data.Message{}).ListByPhone(app.db, in.From)
+	if err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		if msg.SenderName == in.To {
+			in.SenderUuid = msg.SenderUuid
+			return nil
+		}
+	}
+	if len(messages) > 0 {
+		in.SenderUuid = messages[0].SenderUuid
+	}
+	return nil
+}
+
+// forwardInbound posts inbound message to the MO callback URL of its sender and to its SMPP receiver
+func (app *App) forwardInbound(in *data.Inbound) {
+	if in.SenderUuid == uuid.Nil {
+		return
+	}
+	app.smpp.DeliverInbound(in)
+	sender := &data.Sender{}
+	if err := sender.LoadById(app.db, in.SenderUuid); err != nil {
+		log.Printf("Can't load sender for inbound message %s: %v", in.InboundUuid, err)
+		return
+	}
+	if len(sender.MoCallbackUrl) == 0 {
+		return
+	}
+	body, _ := json.Marshal((&InboundOut{}).FromModel(in))
+	hook := &data.Webhook{
+		SenderUuid:  sender.SenderUuid,
+		Url:         sender.MoCallbackUrl,
+		ContentType: "application/json",
+		Body:        string(body),
+	}
+	if err := app.webhooks.Enqueue(hook); err != nil {
+		log.Printf("Can't enqueue inbound message %s to %s: %v", in.InboundUuid, sender.MoCallbackUrl, err)
+	}
+}
+
+// ListInbound godoc
+// @Summary List inbound messages
+// @Param phoneNumber query string false "Phone number of the handset"
+// @Param limit query string false "Limit, default 10"
+// @Param offset query string false "Offset, default 0"
+// @Success 200 {array} InboundOut
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /inbound [get]
+func (app *App) ListInbound(c *gin.Context) {
+	limit, offset, ok := parseLimitOffset(c)
+	if !ok {
+		return
+	}
+	retdata, err := (&data.Inbound{}).List(app.db, c.Query("phoneNumber"), limit, offset)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list inbound messages: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list messages due to internal server error"})
+		return
+	}
+	res := make([]*InboundOut, len(retdata))
+	for i := 0; i < len(retdata); i++ {
+		res[i] = (&InboundOut{}).FromModel(retdata[i])
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetInbound godoc
+// @Summary Get inbound message
+// @Param inboundUuid path string true "Inbound message ID"
+// @Success 200 {object} InboundOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /inbound/{inboundUuid} [get]
+func (app *App) GetInbound(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("inboundUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse inbound message uuid"})
+		return
+	}
+	in := &data.Inbound{}
+	if err = in.LoadById(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't load inbound message: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find message"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't get message due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, (&InboundOut{}).FromModel(in))
+}
+
+// DeleteInbound godoc
+// @Summary Delete inbound message
+// @Param inboundUuid path string true "Inbound message ID"
+// @Success 204
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /inbound/{inboundUuid} [delete]
+func (app *App) DeleteInbound(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("inboundUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse inbound message uuid"})
+		return
+	}
+	if err = (&data.Inbound{}).Delete(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't delete inbound message from database: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested message"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't delete message due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{})
+}
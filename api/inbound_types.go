@@ -0,0 +1,38 @@
+package api
+
+import (
+	"github.com/google/uuid"
+	"smsgate-mock/data"
+	"time"
+)
+
+type InboundIn struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Text string `json:"text"`
+	// SenderUuid of the owning sender, found by the latest outbound message to From if empty
+	SenderUuid uuid.UUID `json:"senderUuid"`
+}
+
+func (s *InboundIn) ToModel() *data.Inbound {
+	return &data.Inbound{From: s.From, To: s.To, Text: s.Text, SenderUuid: s.SenderUuid}
+}
+
+type InboundOut struct {
+	InboundUuid uuid.UUID `json:"inboundUuid"`
+	SenderUuid  uuid.UUID `json:"senderUuid"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Text        string    `json:"text"`
+	Create      time.Time `json:"created"`
+}
+
+func (s *InboundOut) FromModel(src *data.Inbound) *InboundOut {
+	s.InboundUuid = src.InboundUuid
+	s.SenderUuid = src.SenderUuid
+	s.From = src.From
+	s.To = src.To
+	s.Text = src.Text
+	s.Create = src.Create
+	return s
+}
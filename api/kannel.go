@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"smsgate-mock/data"
+	"strconv"
+	"strings"
+)
+
+// KannelSendSms godoc
+// @Summary Create new SMS, Kannel dialect
+// @Produce plain
+// @Param username query string true "Sender's login"
+// @Param password query string true "Sender's password"
+// @Param to query string true "Phone number"
+// @Param from query string false "Sender name"
+// @Param text query string true "Message text"
+// @Param dlr-mask query int false "Statuses to report: 1 delivered, 2 failed, 4 buffered, 8 smsc ack, 16 smsc reject"
+// @Param dlr-url query string false "Delivery report URL with %d, %p, %P, %t, %T, %I, %A escape codes"
+// @Param validity query int false "Validity period in minutes"
+// @Success 202 {string} string "0: Accepted for delivery"
+// @Failure 400 {string} string
+// @Failure 403 {string} string "Authorization failed for sendsms"
+// @Router /cgi-bin/sendsms [get]
+func (app *App) KannelSendSms(c *gin.Context) {
+	var req KannelSendIn
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse request: %v", err))
+		c.String(http.StatusBadRequest, kannelInternalFail)
+		return
+	}
+	sender := &data.Sender{}
+	if err := sender.LoadByLogin(app.db, req.Username); err != nil {
+		c.Error(fmt.Errorf("can't load sender: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.String(http.StatusForbidden, kannelAuthFailed)
+		} else {
+			c.String(http.StatusInternalServerError, kannelInternalFail)
+		}
+		return
+	}
+	if sender.Password != req.Password {
+		c.String(http.StatusForbidden, kannelAuthFailed)
+		return
+	}
+	if len(req.To) == 0 {
+		c.String(http.StatusBadRequest, kannelNoReceiver)
+		return
+	}
+	if len(req.Text) == 0 {
+		c.String(http.StatusBadRequest, kannelNoText)
+		return
+	}
+	msg := req.ToModel()
+	msg.Sender = sender
+	if len(req.DlrMask) > 0 {
+		mask, err := strconv.Atoi(req.DlrMask)
+		if err != nil || mask < 0 || mask > 31 {
+			c.String(http.StatusBadRequest, kannelBadDlrMask)
+			return
+		}
+		msg.DlrMask = mask
+	}
+	if len(req.Validity) > 0 {
+		validity, err := strconv.Atoi(req.Validity)
+		if err != nil || validity < 0 {
+			c.String(http.StatusBadRequest, kannelBadValidity)
+			return
+		}
+		msg.ExpirationTimeout = validity * 60
+	}
+	if err := app.smsc.Submit(msg); err != nil {
+		c.Error(fmt.Errorf("can't save message: %v", err))
+		if _, ok := tooLongError(err); ok {
+			c.String(http.StatusBadRequest, kannelTooLong)
+			return
+		}
+		if _, ok := senderNameError(err); ok {
+			c.String(http.StatusForbidden, kannelSenderDenied)
+			return
+		}
+		if _, ok := balanceError(err); ok {
+			c.String(http.StatusPaymentRequired, kannelInternalFail)
+			return
+		}
+		if _, ok := limitError(c, err); ok {
+			c.String(http.StatusTooManyRequests, kannelThrottled)
+			return
+		}
+		c.String(http.StatusInternalServerError, kannelInternalFail)
+		return
+	}
+	c.Header("X-Kannel-Message-Id", msg.MessageUuid.String())
+	c.String(http.StatusAccepted, kannelAccepted)
+}
+
+// sendKannelDlr calls dlr-url of the message if its dlr-mask has the current status
+func (app *App) sendKannelDlr(msg *data.Message) {
+	dlrType := KannelDlrType(msg.Status)
+	if len(msg.CallbackUrl) == 0 || msg.DlrMask&dlrType == 0 {
+		return
+	}
+	hook := &data.Webhook{
+		SenderUuid: msg.SenderUuid,
+		Method:     http.MethodGet,
+		Url:        KannelDlrUrl(msg.CallbackUrl, msg, dlrType),
+	}
+	if err := app.webhooks.Enqueue(hook); err != nil {
+		log.Printf("Can't enqueue dlr of %s to %s: %v", msg.MessageUuid, hook.Url, err)
+	}
+}
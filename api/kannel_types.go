@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/url"
+	"smsgate-mock/data"
+	"strconv"
+	"strings"
+)
+
+const KannelDialect = "kannel"
+
+// Kannel dlr-mask bits
+const (
+	KannelDlrDelivered = 1
+	KannelDlrFailed    = 2
+	KannelDlrBuffered  = 4
+	KannelDlrSmscAck   = 8
+	KannelDlrSmscNack  = 16
+)
+
+// plain-text responses of Kannel's sendsms interface
+const (
+	kannelAccepted     = "0: Accepted for delivery"
+	kannelAuthFailed   = "Authorization failed for sendsms"
+	kannelNoReceiver   = "Missing receiver number for sendsms"
+	kannelNoText       = "Missing text for sendsms"
+	kannelBadDlrMask   = "Invalid dlr-mask for sendsms"
+	kannelBadValidity  = "Invalid validity for sendsms"
+	kannelInternalFail = "Sending failed."
+	kannelThrottled    = "Throttling error"
+	kannelTooLong      = "Message too long for sendsms"
+	kannelSenderDenied = "Sender not allowed"
+)
+
+type KannelSendIn struct {
+	Username string `form:"username"`
+	Password string `form:"password"`
+	To       string `form:"to"`
+	From     string `form:"from"`
+	Text     string `form:"text"`
+	DlrMask  string `form:"dlr-mask"`
+	DlrUrl   string `form:"dlr-url"`
+	// Validity in minutes
+	Validity string `form:"validity"`
+}
+
+func (s *KannelSendIn) ToModel() *data.Message {
+	return &data.Message{
+		SenderName:  s.From,
+		MessageType: "TEXT",
+		MessageText: s.Text,
+		PhoneNumber: s.To,
+		CallbackUrl: s.DlrUrl,
+		Dialect:     KannelDialect,
+	}
+}
+
+// KannelDlrType converts message status to dlr type, 0 if it has no equivalent
+func KannelDlrType(status string) int {
+	switch status {
+	case data.StatusDelivered:
+		return KannelDlrDelivered
+	case data.StatusUndelivered, data.StatusExpired:
+		return KannelDlrFailed
+	case data.StatusSent:
+		return KannelDlrBuffered
+	case data.StatusAccepted:
+		return KannelDlrSmscAck
+	case data.StatusRejected:
+		return KannelDlrSmscNack
+	}
+	return 0
+}
+
+// KannelDlrUrl substitutes Kannel's escape codes in dlr-url with values of the message
+func KannelDlrUrl(template string, msg *data.Message, dlrType int) string {
+	// the report time is the time of the status change, not of the (possibly retried) callback
+	changed := msg.Updated
+	values := map[byte]string{
+		'd': strconv.Itoa(dlrType),
+		'p': msg.PhoneNumber,
+		'P': msg.SenderName,
+		'a': msg.MessageText,
+		'b': msg.MessageText,
+		't': changed.Format("2006-01-02 15:04:05"),
+		'T': strconv.FormatInt(changed.Unix(), 10),
+		'I': msg.MessageUuid.String(),
+		'F': msg.MessageUuid.String(),
+		'A': msg.Status,
+		'%': "%",
+	}
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] == '%' && i+1 < len(template) {
+			if v, ok := values[template[i+1]]; ok {
+				if template[i+1] != '%' {
+					v = url.QueryEscape(v)
+				}
+				b.WriteString(v)
+				i++
+				continue
+			}
+		}
+		b.WriteByte(template[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"smsgate-mock/data"
+	"smsgate-mock/phone"
+	"strings"
+)
+
+// Lookup godoc
+// @Summary HLR lookup of phone number in virtual subscriber table
+// @Produce json
+// @Param phoneNumber path string true "Phone number"
+// @Success 200 {object} LookupOut
+// @Failure 500 {object} ErrorMessage
+// @Router /lookup/{phoneNumber} [get]
+func (app *App) Lookup(c *gin.Context) {
+	lookup, err := app.smsc.Lookup(c.Param("phoneNumber"))
+	if err != nil {
+		c.Error(fmt.Errorf("can't look up number: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't look up number due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, (&LookupOut{}).FromModel(lookup))
+}
+
+// phoneNumberParam normalizes the number from the path, writes error response if it fails
+func (app *App) phoneNumberParam(c *gin.Context) (string, bool) {
+	e164, err := phone.Normalize(c.Param("phoneNumber"), app.cfg.DefaultCountry)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad phone number: " + err.Error()})
+		return "", false
+	}
+	return e164, true
+}
+
+// ListSubscribers godoc
+// @Summary List virtual subscribers
+// @Produce json
+// @Param limit query string false "Limit, default 10"
+// @Param offset query string false "Offset, default 0"
+// @Success 200 {array} SubscriberOut
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /subscriber [get]
+func (app *App) ListSubscribers(c *gin.Context) {
+	limit, offset, ok := parseLimitOffset(c)
+	if !ok {
+		return
+	}
+	subscribers, err := (&data.Subscriber{}).List(app.db, limit, offset)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list subscribers: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list subscribers due to internal server error"})
+		return
+	}
+	res := make([]*SubscriberOut, len(subscribers))
+	for i, subscriber := range subscribers {
+		res[i] = (&SubscriberOut{}).FromModel(subscriber)
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// SetSubscriber godoc
+// @Summary Create or replace virtual subscriber
+// @Produce json
+// @Param phoneNumber path string true "Phone number"
+// @Param subscriber body SubscriberIn true "Subscriber"
+// @Success 200 {object} SubscriberOut
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /subscriber/{phoneNumber} [put]
+func (app *App) SetSubscriber(c *gin.Context) {
+	e164, ok := app.phoneNumberParam(c)
+	if !ok {
+		return
+	}
+	var req SubscriberIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	if len(req.PortedTo) > 0 && phone.CarrierById(req.PortedTo) == nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad subscriber: unknown carrier " + req.PortedTo})
+		return
+	}
+	subscriber := req.ToModel(e164)
+	if err := subscriber.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad subscriber: " + err.Error()})
+		return
+	}
+	if err := subscriber.Save(app.db); err != nil {
+		c.Error(fmt.Errorf("can't save subscriber: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't save subscriber due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, (&SubscriberOut{}).FromModel(subscriber))
+}
+
+// GetSubscriber godoc
+// @Summary Get virtual subscriber
+// @Produce json
+// @Param phoneNumber path string true "Phone number"
+// @Success 200 {object} SubscriberOut
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /subscriber/{phoneNumber} [get]
+func (app *App) GetSubscriber(c *gin.Context) {
+	e164, ok := app.phoneNumberParam(c)
+	if !ok {
+		return
+	}
+	subscriber := &data.Subscriber{}
+	found, err := subscriber.Load(app.db, e164)
+	if err != nil {
+		c.Error(fmt.Errorf("can't load subscriber: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load subscriber due to internal server error"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested subscriber"})
+		return
+	}
+	c.JSON(http.StatusOK, (&SubscriberOut{}).FromModel(subscriber))
+}
+
+// DeleteSubscriber godoc
+// @Summary Delete virtual subscriber
+// @Param phoneNumber path string true "Phone number"
+// @Success 204
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /subscriber/{phoneNumber} [delete]
+func (app *App) DeleteSubscriber(c *gin.Context) {
+	e164, ok := app.phoneNumberParam(c)
+	if !ok {
+		return
+	}
+	if err := (&data.Subscriber{}).Delete(app.db, e164); err != nil {
+		c.Error(fmt.Errorf("can't delete subscriber: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested subscriber"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't delete subscriber due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{})
+}
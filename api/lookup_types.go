@@ -0,0 +1,103 @@
+package api
+
+import (
+	"smsgate-mock/data"
+	"smsgate-mock/phone"
+	"smsgate-mock/smsc"
+	"time"
+)
+
+type LookupCarrierOut struct {
+	// CarrierId is MCC and MNC
+	CarrierId string `json:"carrierId"`
+	Mcc       string `json:"mcc"`
+	Mnc       string `json:"mnc"`
+	Name      string `json:"name"`
+}
+
+func (s *LookupCarrierOut) FromModel(src *phone.Carrier) *LookupCarrierOut {
+	s.CarrierId = src.Id()
+	s.Mcc = src.Mcc
+	s.Mnc = src.Mnc
+	s.Name = src.Name
+	return s
+}
+
+type LookupOut struct {
+	// PhoneNumber is in E.164 format, empty if the number can't be parsed
+	PhoneNumber string `json:"phoneNumber"`
+	Valid       bool   `json:"valid"`
+	// Reason why the number isn't valid
+	Reason  string `json:"reason,omitempty"`
+	Country string `json:"country,omitempty"`
+	// LineType is MOBILE, LANDLINE or VOIP, empty if it's unknown
+	LineType string `json:"lineType,omitempty"`
+	// OriginalCarrier is the one the number range was allocated to, PortedCarrier serves the number now
+	OriginalCarrier *LookupCarrierOut `json:"originalCarrier,omitempty"`
+	PortedCarrier   *LookupCarrierOut `json:"portedCarrier,omitempty"`
+	Ported          bool              `json:"ported"`
+	Roaming         bool              `json:"roaming"`
+	RoamingCountry  string            `json:"roamingCountry,omitempty"`
+	// Status is ACTIVE, ABSENT or UNKNOWN
+	Status    string `json:"status"`
+	Reachable bool   `json:"reachable"`
+}
+
+func (s *LookupOut) FromModel(src *smsc.Lookup) *LookupOut {
+	s.PhoneNumber = src.PhoneNumber
+	s.Valid = src.Valid
+	s.Reason = src.Reason
+	s.Country = src.Country
+	s.LineType = src.LineType
+	if src.Carrier != nil {
+		s.OriginalCarrier = (&LookupCarrierOut{}).FromModel(src.Carrier)
+	}
+	if src.Ported != nil {
+		s.PortedCarrier = (&LookupCarrierOut{}).FromModel(src.Ported)
+		s.Ported = true
+	}
+	s.Roaming = len(src.Roaming) > 0
+	s.RoamingCountry = src.Roaming
+	s.Status = src.Status
+	s.Reachable = src.Reachable
+	return s
+}
+
+type SubscriberIn struct {
+	// LineType is MOBILE (default), LANDLINE or VOIP
+	LineType string `json:"lineType,omitempty"`
+	// Status is ACTIVE (default), ABSENT or UNKNOWN for a number which isn't allocated
+	Status string `json:"status,omitempty"`
+	// PortedTo is MCC and MNC of the carrier the number was ported to
+	PortedTo string `json:"portedTo,omitempty"`
+	// Roaming is the country the subscriber visits
+	Roaming string `json:"roaming,omitempty"`
+}
+
+func (s *SubscriberIn) ToModel(phoneNumber string) *data.Subscriber {
+	return &data.Subscriber{
+		PhoneNumber: phoneNumber,
+		LineType:    s.LineType,
+		Status:      s.Status,
+		PortedTo:    s.PortedTo,
+		Roaming:     s.Roaming,
+	}
+}
+
+type SubscriberOut struct {
+	PhoneNumber string `json:"phoneNumber"`
+	SubscriberIn
+	Create  time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+func (s *SubscriberOut) FromModel(src *data.Subscriber) *SubscriberOut {
+	s.PhoneNumber = src.PhoneNumber
+	s.LineType = src.LineType
+	s.Status = src.Status
+	s.PortedTo = src.PortedTo
+	s.Roaming = src.Roaming
+	s.Create = src.Create
+	s.Updated = src.Updated
+	return s
+}
@@ -15,12 +15,24 @@ import (
 // @Param message body MessageIn true "Message data"
 // @Success 201 {object} MessageOut
 // @Failure 404 {object} ErrorMessage
-// @Failure 422 {object} ErrorMessage
+// @Failure 422 {object} ValidationErrorMessage
 // @Failure 401 {object} ErrorMessage
+// @Failure 402 {object} BalanceErrorMessage
+// @Failure 403 {object} SenderNameErrorMessage
+// @Failure 429 {object} LimitErrorMessage
 // @Router /message [post]
 func (app *App) Message(c *gin.Context) {
 	var req MessageIn
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if app.cfg.StrictValidation {
+		errs := bindStrict(c, &req)
+		if len(errs) == 0 || errs[0].Field != "" {
+			errs = mergeFieldErrors(errs, req.Validate(app.cfg.DefaultCountry))
+		}
+		if len(errs) > 0 {
+			validationFailed(c, errs)
+			return
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
 		c.Error(fmt.Errorf("can't parse JSON: %v", err))
 		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
 		return
@@ -41,8 +53,24 @@ func (app *App) Message(c *gin.Context) {
 	}
 	msg := req.ToModel()
 	msg.Sender = sender
-	if err := msg.Save(app.db); err != nil {
+	if err := app.smsc.Submit(msg); err != nil {
 		c.Error(fmt.Errorf("can't save message: %v", err))
+		if _, ok := tooLongError(err); ok {
+			c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't send message: " + err.Error()})
+			return
+		}
+		if nameErr, ok := senderNameError(err); ok {
+			c.JSON(http.StatusForbidden, (&SenderNameErrorMessage{}).FromModel(nameErr))
+			return
+		}
+		if balance, ok := balanceError(err); ok {
+			c.JSON(http.StatusPaymentRequired, (&BalanceErrorMessage{}).FromModel(balance))
+			return
+		}
+		if limit, ok := limitError(c, err); ok {
+			c.JSON(http.StatusTooManyRequests, (&LimitErrorMessage{}).FromModel(limit))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't send message due to internal server error"})
 		return
 	}
@@ -107,6 +135,37 @@ func (app *App) DeleteMessage(c *gin.Context) {
 	c.JSON(http.StatusNoContent, gin.H{})
 }
 
+// CancelMessage godoc
+// @Summary Cancel scheduled or queued message which hasn't gone out yet
+// @Produce json
+// @Param messageUuid path string true "Message ID"
+// @Success 200 {object} MessageStatusOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 409 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /message/{messageUuid}/cancel [post]
+func (app *App) CancelMessage(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("messageUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse message uuid"})
+		return
+	}
+	msg, err := app.smsc.Cancel(id)
+	if err != nil {
+		c.Error(fmt.Errorf("can't cancel message: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested message"})
+		} else if strings.Contains(err.Error(), "can't be canceled") {
+			c.JSON(http.StatusConflict, &ErrorMessage{"Message has already gone out: " + err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't cancel message due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, (&MessageStatusOut{}).FromModel(msg))
+}
+
 // SearchMessage godoc
 // @Summary Search messages by phone number
 // @Param phoneNumber query string true "Phone number"
@@ -131,15 +190,45 @@ func (app *App) SearchMessage(c *gin.Context) {
 // @Summary List messages
 // @Param limit query string false "Limit, default 10"
 // @Param offset query string false "Offset, default 0"
+// @Param country query string false "Destination country"
+// @Param carrier query string false "Carrier name or MCC and MNC"
 // @Success 200 {array} ListMessageOut
 // @Failure 422 {object} ErrorMessage
 // @Failure 500 {object} ErrorMessage
 // @Router /message [get]
 func (app *App) ListMessage(c *gin.Context) {
+	limit, offset, ok := parseLimitOffset(c)
+	if !ok {
+		return
+	}
+	var retdata []*data.Message
+	var err error
+	country, carrier := strings.ToUpper(c.Query("country")), c.Query("carrier")
+	if len(country) > 0 || len(carrier) > 0 {
+		retdata, err = (&data.Message{}).ListFiltered(app.db, "", func(msg *data.Message) bool {
+			return (len(country) == 0 || msg.Country == country) &&
+				(len(carrier) == 0 || msg.Carrier == carrier || msg.Mcc+msg.Mnc == carrier)
+		}, limit, offset)
+	} else {
+		retdata, err = (&data.Message{}).List(app.db, limit, offset)
+	}
+	if err != nil {
+		c.Error(fmt.Errorf("can't list messages: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list messages due to internal server error"})
+	}
+	res := make([]*ListMessageOut, len(retdata))
+	for i := 0; i < len(retdata); i++ {
+		res[i] = (&ListMessageOut{}).FromModel(retdata[i])
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// parseLimitOffset reads pagination from query, writes error response if it fails
+func parseLimitOffset(c *gin.Context) (limit int, offset int, ok bool) {
 	limitS := c.Query("limit")
 	offsetS := c.Query("offset")
-	limit := 10
-	offset := 0
+	limit = 10
+	offset = 0
 	var err error
 	if len(limitS) > 0 {
 		limit, err = strconv.Atoi(limitS)
@@ -157,14 +246,5 @@ func (app *App) ListMessage(c *gin.Context) {
 			return
 		}
 	}
-	retdata, err := (&data.Message{}).List(app.db, limit, offset)
-	if err != nil {
-		c.Error(fmt.Errorf("can't list messages: %v", err))
-		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list messages due to internal server error"})
-	}
-	res := make([]*ListMessageOut, len(retdata))
-	for i := 0; i < len(retdata); i++ {
-		res[i] = (&ListMessageOut{}).FromModel(retdata[i])
-	}
-	c.JSON(http.StatusOK, res)
+	return limit, offset, true
 }
@@ -12,12 +12,19 @@ type MessageIn struct {
 	SenderName string `json:"senderName"`
 	MessageType string `json:"messageType"`
 	MessageText string `json:"messageText"`
+	// ExpirationTimeout in seconds, after it undelivered message becomes EXPIRED
 	ExpirationTimeout int `json:"expirationTimeout"`
 	PhoneNumber string `json:"phoneNumber"`
+	// CallbackUrl for delivery reports, sender's callback URL is used if empty
+	CallbackUrl string `json:"callbackUrl,omitempty"`
+	// Transliterate replaces non GSM-7 characters with lookalikes, sender's option is used if not set
+	Transliterate *bool `json:"transliterate,omitempty"`
+	// SendAt keeps the message SCHEDULED until the time
+	SendAt *time.Time `json:"sendAt,omitempty"`
 }
 
 func (s *MessageIn) ToModel() *data.Message {
-	return &data.Message{
+	msg := &data.Message{
 		Sender: &data.Sender{
 			Login: s.Login,
 			Password: s.Password,
@@ -27,32 +34,89 @@ func (s *MessageIn) ToModel() *data.Message {
 		MessageText: s.MessageText,
 		ExpirationTimeout: s.ExpirationTimeout,
 		PhoneNumber: s.PhoneNumber,
+		CallbackUrl: s.CallbackUrl,
+		Transliterate: s.Transliterate,
 	}
+	if s.SendAt != nil {
+		msg.SendAt = *s.SendAt
+	}
+	return msg
 }
 
 type MessageOut struct {
 	MessageUuid uuid.UUID `json:"messageUuid"`
 	Status string `json:"status"`
 	Create time.Time `json:"created"`
+	// Encoding is GSM7 or UCS2, Parts are the text split into Segments as they are sent
+	Encoding string `json:"encoding"`
+	Segments int `json:"segments"`
+	Parts []string `json:"parts"`
+	// MessageText is the transliterated text, OriginalText and SegmentsSaved are set when it was changed
+	MessageText string `json:"messageText,omitempty"`
+	OriginalText string `json:"originalText,omitempty"`
+	SegmentsSaved int `json:"segmentsSaved,omitempty"`
+	// Price is debited from the sender's balance
+	Price float64 `json:"price,omitempty"`
+	SendAt *time.Time `json:"sendAt,omitempty"`
 }
 
 func (s *MessageOut) FromModel(src *data.Message)  *MessageOut {
 	s.MessageUuid = src.MessageUuid
 	s.Status = src.Status
 	s.Create = src.Create
+	s.Encoding = src.Encoding
+	s.Segments = src.Segments
+	s.Parts = src.Parts
+	s.Price = src.Price
+	if src.OriginalText != "" {
+		s.MessageText = src.MessageText
+		s.OriginalText = src.OriginalText
+		s.SegmentsSaved = src.OriginalSegments - src.Segments
+	}
+	if !src.SendAt.IsZero() {
+		s.SendAt = &src.SendAt
+	}
 	return s
 }
 
 type MessageStatusOut struct {
 	MessageUuid uuid.UUID `json:"messageUuid"`
 	Status string `json:"status"`
+	Create time.Time `json:"created"`
 	Sent time.Time `json:"sent"`
+	Updated time.Time `json:"updated"`
+	Done time.Time `json:"done"`
+	Expires time.Time `json:"expires"`
+	ErrorCode int `json:"errorCode,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	// Rule is a name of the rule which set the outcome
+	Rule string `json:"rule,omitempty"`
+	Price float64 `json:"price,omitempty"`
+	// Held is set while the message is stored for an unavailable handset
+	Held bool `json:"held,omitempty"`
+	SendAt *time.Time `json:"sendAt,omitempty"`
+	BatchUuid *uuid.UUID `json:"batchUuid,omitempty"`
 }
 
 func (s *MessageStatusOut) FromModel(src *data.Message) *MessageStatusOut {
 	s.MessageUuid = src.MessageUuid
 	s.Status = src.Status
+	s.Create = src.Create
 	s.Sent = src.Sent
+	s.Updated = src.Updated
+	s.Done = src.Done
+	s.Expires = src.Expires
+	s.ErrorCode = src.ErrorCode
+	s.ErrorMessage = src.ErrorMessage
+	s.Rule = src.Rule
+	s.Price = src.Price
+	s.Held = src.Held
+	if !src.SendAt.IsZero() {
+		s.SendAt = &src.SendAt
+	}
+	if src.BatchUuid != uuid.Nil {
+		s.BatchUuid = &src.BatchUuid
+	}
 	return s
 }
 
@@ -62,7 +126,19 @@ type ListMessageOut struct {
 	MessageText string `json:"messageText"`
 	ExpirationTimeout int `json:"expirationTimeout"`
 	PhoneNumber string `json:"phoneNumber"`
+	Status string `json:"status"`
 	Sent time.Time `json:"sent"`
+	Updated time.Time `json:"updated"`
+	Encoding string `json:"encoding"`
+	Segments int `json:"segments"`
+	Parts []string `json:"parts"`
+	OriginalText string `json:"originalText,omitempty"`
+	SegmentsSaved int `json:"segmentsSaved,omitempty"`
+	// Country, Mcc, Mnc and Carrier are resolved from the phone number
+	Country string `json:"country,omitempty"`
+	Mcc string `json:"mcc,omitempty"`
+	Mnc string `json:"mnc,omitempty"`
+	Carrier string `json:"carrier,omitempty"`
 }
 
 func (s *ListMessageOut) FromModel(src *data.Message) *ListMessageOut {
@@ -71,6 +147,33 @@ func (s *ListMessageOut) FromModel(src *data.Message) *ListMessageOut {
 	s.MessageType = src.MessageType
 	s.ExpirationTimeout = src.ExpirationTimeout
 	s.PhoneNumber = src.PhoneNumber
+	s.Status = src.Status
 	s.Sent = src.Sent
+	s.Updated = src.Updated
+	s.Encoding = src.Encoding
+	s.Segments = src.Segments
+	s.Parts = src.Parts
+	if src.OriginalText != "" {
+		s.OriginalText = src.OriginalText
+		s.SegmentsSaved = src.OriginalSegments - src.Segments
+	}
+	s.Country = src.Country
+	s.Mcc = src.Mcc
+	s.Mnc = src.Mnc
+	s.Carrier = src.Carrier
+	return s
+}
+
+// DeliveryReportOut is posted to the callback URL on every status change
+type DeliveryReportOut struct {
+	MessageStatusOut
+	PhoneNumber string `json:"phoneNumber"`
+	SenderName string `json:"senderName"`
+}
+
+func (s *DeliveryReportOut) FromModel(src *data.Message) *DeliveryReportOut {
+	s.MessageStatusOut.FromModel(src)
+	s.PhoneNumber = src.PhoneNumber
+	s.SenderName = src.SenderName
 	return s
 }
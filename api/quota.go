@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"math"
+	"net/http"
+	"smsgate-mock/data"
+	"smsgate-mock/smsc"
+	"strconv"
+	"strings"
+)
+
+// limitError returns the limit error of Submit and sets Retry-After header for it
+func limitError(c *gin.Context, err error) (*smsc.LimitError, bool) {
+	var limit *smsc.LimitError
+	if !errors.As(err, &limit) {
+		return nil, false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(limit.RetryAfter.Seconds())))))
+	return limit, true
+}
+
+// tooLongError returns the error of Submit if the message has too many parts
+func tooLongError(err error) (*smsc.TooLongError, bool) {
+	var tooLong *smsc.TooLongError
+	return tooLong, errors.As(err, &tooLong)
+}
+
+// SenderQuota godoc
+// @Summary Get sender's rate limit, quotas and their usage
+// @Produce json
+// @Param senderUuid path string true "Sender ID"
+// @Success 200 {object} QuotaOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /sender/{senderUuid}/quota [get]
+func (app *App) SenderQuota(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("senderUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse sender uuid"})
+		return
+	}
+	sender := &data.Sender{}
+	if err := sender.LoadById(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't load sender: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested sender"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load quota due to internal server error"})
+		}
+		return
+	}
+	usage, tokens, err := app.smsc.Quota(sender)
+	if err != nil {
+		c.Error(fmt.Errorf("can't load usage: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load quota due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, (&QuotaOut{}).FromModel(sender, usage, tokens))
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"smsgate-mock/data"
+	"smsgate-mock/smsc"
+	"time"
+)
+
+// limit error codes
+const (
+	CodeThrottled            = "THROTTLED"
+	CodeDailyQuotaExceeded   = "DAILY_QUOTA_EXCEEDED"
+	CodeMonthlyQuotaExceeded = "MONTHLY_QUOTA_EXCEEDED"
+)
+
+// LimitErrorMessage is returned with 429 when the sender exceeds its rate limit or quota
+type LimitErrorMessage struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+func (s *LimitErrorMessage) FromModel(src *smsc.LimitError) *LimitErrorMessage {
+	s.Error = src.Error()
+	switch src.Limit {
+	case smsc.LimitDaily:
+		s.Code = CodeDailyQuotaExceeded
+	case smsc.LimitMonthly:
+		s.Code = CodeMonthlyQuotaExceeded
+	default:
+		s.Code = CodeThrottled
+	}
+	return s
+}
+
+type QuotaOut struct {
+	// RateLimit is messages per second, RateBurst is a bucket size, Tokens are messages allowed right now
+	RateLimit float64 `json:"rateLimit"`
+	RateBurst int     `json:"rateBurst"`
+	Tokens    float64 `json:"tokens"`
+	// quotas and remaining messages are -1 when unlimited
+	DailyQuota       int       `json:"dailyQuota"`
+	DailyUsed        int       `json:"dailyUsed"`
+	DailyRemaining   int       `json:"dailyRemaining"`
+	DailyReset       time.Time `json:"dailyReset"`
+	MonthlyQuota     int       `json:"monthlyQuota"`
+	MonthlyUsed      int       `json:"monthlyUsed"`
+	MonthlyRemaining int       `json:"monthlyRemaining"`
+	MonthlyReset     time.Time `json:"monthlyReset"`
+}
+
+func remaining(quota, used int) int {
+	if quota <= 0 {
+		return -1
+	}
+	if used >= quota {
+		return 0
+	}
+	return quota - used
+}
+
+func quotaOrUnlimited(quota int) int {
+	if quota <= 0 {
+		return -1
+	}
+	return quota
+}
+
+func (s *QuotaOut) FromModel(sender *data.Sender, usage *data.Usage, tokens float64) *QuotaOut {
+	now := time.Now()
+	s.RateLimit = sender.RateLimit
+	s.RateBurst = sender.RateBurst
+	s.Tokens = tokens
+	s.DailyQuota = quotaOrUnlimited(sender.DailyQuota)
+	s.DailyUsed = usage.Daily
+	s.DailyRemaining = remaining(sender.DailyQuota, usage.Daily)
+	s.DailyReset = data.QuotaReset(data.QuotaDaily, now)
+	s.MonthlyQuota = quotaOrUnlimited(sender.MonthlyQuota)
+	s.MonthlyUsed = usage.Monthly
+	s.MonthlyRemaining = remaining(sender.MonthlyQuota, usage.Monthly)
+	s.MonthlyReset = data.QuotaReset(data.QuotaMonthly, now)
+	return s
+}
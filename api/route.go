@@ -10,21 +10,51 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"smsgate-mock/dialect"
+	"smsgate-mock/smpp"
+	"smsgate-mock/smsc"
 	"smsgate-mock/utils"
+	"smsgate-mock/webhook"
 	"strings"
 	"time"
 )
 
 type App struct {
-	cfg *utils.Settings
-	r   *gin.Engine
-	db  *bbolt.DB
+	cfg      *utils.Settings
+	r        *gin.Engine
+	db       *bbolt.DB
+	smsc     *smsc.Center
+	webhooks *webhook.Dispatcher
+	smpp     *smpp.Server
+	dialects map[string]*dialect.Dialect
+	server   *http.Server
+	// backpressure bounds concurrent API requests
+	backpressure *backpressure
 }
 
 func Init(cfg *utils.Settings, db *bbolt.DB) *App {
-	app := &App{cfg: cfg, r: gin.New(), db: db}
+	app := &App{
+		cfg:      cfg,
+		r:        gin.New(),
+		db:       db,
+		smsc:     smsc.New(cfg, db),
+		webhooks: webhook.New(cfg, db),
+	}
+	app.backpressure = &backpressure{
+		maxInFlight:  cfg.MaxInFlight,
+		maxQueue:     cfg.MaxQueue,
+		queueTimeout: cfg.QueueTimeout,
+		retryAfter:   cfg.OverloadRetryAfter,
+	}
+	app.server = &http.Server{Handler: app.r, ConnContext: withConn}
+	app.smpp = smpp.New(cfg, db, app.smsc)
+	app.loadDialects()
 	app.setupRoutes()
+	app.smsc.Subscribe(app.sendDeliveryReport)
+	go app.smsc.Run()
+	go app.webhooks.Run()
 	return app
 }
 
@@ -37,16 +67,80 @@ func (app *App) setupRoutes() {
 		app.r.Use(ResponseLoggerMiddleware)
 	}
 	app.r.Use(gin.LoggerWithFormatter(customFormatter))
+	app.r.Use(app.BackpressureMiddleware)
+	app.r.Use(app.FaultMiddleware)
 	api_r := app.r.Group("/api/v1")
 	api_r.GET("/sender", app.ListSenders)
 	api_r.POST("/sender", app.AddSender)
 	api_r.DELETE("/sender/:senderUuid", app.DeleteSender)
 	api_r.PATCH("/sender/:senderUuid", app.EditSender)
 	api_r.POST("/sender/check_connection/:senderUuid", app.CheckConnection)
+	api_r.GET("/sender/:senderUuid/quota", app.SenderQuota)
+	api_r.GET("/sender_name", app.ListSenderNames)
+	api_r.POST("/sender_name", app.AddSenderName)
+	api_r.GET("/sender_name/:senderNameUuid", app.GetSenderName)
+	api_r.PATCH("/sender_name/:senderNameUuid", app.EditSenderName)
+	api_r.DELETE("/sender_name/:senderNameUuid", app.DeleteSenderName)
+	api_r.GET("/account/:senderUuid", app.GetAccount)
+	api_r.POST("/account/:senderUuid/topup", app.TopUp)
+	api_r.GET("/account/:senderUuid/ledger", app.ListLedger)
+	api_r.GET("/price", app.ListPrices)
+	api_r.POST("/price", app.AddPrice)
+	api_r.GET("/price/:priceUuid", app.GetPrice)
+	api_r.DELETE("/price/:priceUuid", app.DeletePrice)
 	api_r.POST("/message", app.Message)
 	api_r.GET("/message", app.ListMessage)
 	api_r.DELETE("/message/:messageUuid", app.DeleteMessage)
 	api_r.GET("/message/:messageUuid", app.MessageStatus)
+	api_r.POST("/message/:messageUuid/cancel", app.CancelMessage)
+	api_r.POST("/batch", app.Batch)
+	api_r.GET("/batch/:batchUuid", app.BatchStatus)
+	api_r.POST("/campaign", app.AddCampaign)
+	api_r.GET("/campaign", app.ListCampaigns)
+	api_r.GET("/campaign/:campaignUuid", app.GetCampaign)
+	api_r.POST("/campaign/:campaignUuid/pause", app.PauseCampaign)
+	api_r.POST("/campaign/:campaignUuid/resume", app.ResumeCampaign)
+	api_r.POST("/campaign/:campaignUuid/abort", app.AbortCampaign)
+	api_r.POST("/inbound", app.Inbound)
+	api_r.GET("/inbound", app.ListInbound)
+	api_r.GET("/inbound/:inboundUuid", app.GetInbound)
+	api_r.DELETE("/inbound/:inboundUuid", app.DeleteInbound)
+	api_r.GET("/webhook", app.ListWebhooks)
+	api_r.GET("/webhook/dead", app.ListDeadWebhooks)
+	api_r.POST("/webhook/dead/:webhookUuid/replay", app.ReplayWebhook)
+	api_r.DELETE("/webhook/dead/:webhookUuid", app.DeleteDeadWebhook)
+	api_r.GET("/rule", app.ListRules)
+	api_r.POST("/rule", app.AddRule)
+	api_r.GET("/rule/:ruleUuid", app.GetRule)
+	api_r.PUT("/rule/:ruleUuid", app.ReplaceRule)
+	api_r.DELETE("/rule/:ruleUuid", app.DeleteRule)
+	api_r.GET("/carrier", app.ListCarriers)
+	api_r.GET("/carrier/:carrierId", app.GetCarrier)
+	api_r.PUT("/carrier/:carrierId/profile", app.SetCarrierProfile)
+	api_r.DELETE("/carrier/:carrierId/profile", app.DeleteCarrierProfile)
+	api_r.GET("/lookup/:phoneNumber", app.Lookup)
+	api_r.GET("/subscriber", app.ListSubscribers)
+	api_r.PUT("/subscriber/:phoneNumber", app.SetSubscriber)
+	api_r.GET("/subscriber/:phoneNumber", app.GetSubscriber)
+	api_r.DELETE("/subscriber/:phoneNumber", app.DeleteSubscriber)
+	api_r.GET("/handset", app.ListHandsets)
+	api_r.PUT("/handset/:phoneNumber", app.SetHandset)
+	api_r.GET("/handset/:phoneNumber", app.GetHandset)
+	api_r.DELETE("/handset/:phoneNumber", app.DeleteHandset)
+	api_r.GET("/admin/fault", app.ListFaults)
+	api_r.POST("/admin/fault", app.AddFault)
+	api_r.GET("/admin/fault/:faultUuid", app.GetFault)
+	api_r.DELETE("/admin/fault/:faultUuid", app.DeleteFault)
+	api_r.GET("/admin/maintenance", app.GetMaintenance)
+	api_r.PUT("/admin/maintenance", app.SetMaintenance)
+	api_r.GET("/admin/backpressure", app.GetBackpressure)
+	api_r.PUT("/admin/backpressure", app.SetBackpressure)
+	app.r.GET("/cgi-bin/sendsms", app.KannelSendSms)
+	twilio_r := app.r.Group("/"+TwilioApiVersion+"/Accounts/:AccountSid", app.TwilioAuth)
+	twilio_r.POST("/Messages.json", app.TwilioSendMessage)
+	twilio_r.GET("/Messages.json", app.TwilioListMessages)
+	twilio_r.GET("/Messages/:MessageSid", app.TwilioGetMessage)
+	app.mountDialects()
 	app.r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
 
@@ -54,9 +148,41 @@ func (app *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	app.r.ServeHTTP(w, r)
 }
 
+// Serve serves HTTP connections from the listener
+func (app *App) Serve(l net.Listener) error {
+	return app.server.Serve(l)
+}
+
+// ServeSmpp serves SMPP connections from the listener
+func (app *App) ServeSmpp(l net.Listener) error {
+	return app.smpp.Serve(l)
+}
+
+// Close stops background processing
+func (app *App) Close() {
+	app.server.Close()
+	app.smpp.Close()
+	app.smsc.Stop()
+	app.webhooks.Stop()
+}
+
 func (app *App) Run() {
+	if app.cfg.SmppPort > 0 {
+		go func() {
+			if err := app.smpp.ListenAndServe(); err != nil {
+				log.Fatalf("Can't serve SMPP on port %d: %v", app.cfg.SmppPort, err)
+			}
+		}()
+	}
 	addr := fmt.Sprintf(":%d", app.cfg.ListenPort)
-	app.r.Run(addr)
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Can't listen on %s: %v", addr, err)
+	}
+	log.Printf("Listening and serving HTTP on %s", addr)
+	if err := app.Serve(l); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Can't serve HTTP on %s: %v", addr, err)
+	}
 }
 
 func customFormatter(param gin.LogFormatterParams) string {
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"smsgate-mock/data"
+	"strings"
+)
+
+// AddRule godoc
+// @Summary Create new delivery outcome rule
+// @Produce json
+// @Param rule body RuleIn true "New rule"
+// @Success 201 {object} RuleOut
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /rule [post]
+func (app *App) AddRule(c *gin.Context) {
+	var req RuleIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	rule, err := req.ToModel()
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad rule: " + err.Error()})
+		return
+	}
+	if err := rule.Save(app.db); err != nil {
+		c.Error(fmt.Errorf("can't save rule to database: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't save rule due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusCreated, (&RuleOut{}).FromModel(rule))
+}
+
+// ReplaceRule godoc
+// @Summary Replace delivery outcome rule
+// @Produce json
+// @Param ruleUuid path string true "Rule ID"
+// @Param rule body RuleIn true "New version of the rule"
+// @Success 200 {object} RuleOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /rule/{ruleUuid} [put]
+func (app *App) ReplaceRule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("ruleUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse rule uuid"})
+		return
+	}
+	var req RuleIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	rule, err := req.ToModel()
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad rule: " + err.Error()})
+		return
+	}
+	if err := rule.Replace(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't replace rule: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested rule"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't replace rule due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, (&RuleOut{}).FromModel(rule))
+}
+
+// GetRule godoc
+// @Summary Get delivery outcome rule
+// @Produce json
+// @Param ruleUuid path string true "Rule ID"
+// @Success 200 {object} RuleOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /rule/{ruleUuid} [get]
+func (app *App) GetRule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("ruleUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse rule uuid"})
+		return
+	}
+	rule := &data.Rule{}
+	if err := rule.LoadById(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't load rule: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested rule"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load rule due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, (&RuleOut{}).FromModel(rule))
+}
+
+// DeleteRule godoc
+// @Summary Delete delivery outcome rule
+// @Param ruleUuid path string true "Rule ID"
+// @Success 204
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /rule/{ruleUuid} [delete]
+func (app *App) DeleteRule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("ruleUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse rule uuid"})
+		return
+	}
+	if err := (&data.Rule{}).Delete(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't delete rule: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested rule"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't delete rule due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// ListRules godoc
+// @Summary List delivery outcome rules in the order they are checked, magic numbers are the last
+// @Produce json
+// @Success 200 {array} RuleOut
+// @Failure 500 {object} ErrorMessage
+// @Router /rule [get]
+func (app *App) ListRules(c *gin.Context) {
+	retdata, err := (&data.Rule{}).List(app.db)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list rules: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list rules due to internal server error"})
+		return
+	}
+	res := make([]*RuleOut, 0, len(retdata)+len(data.MagicNumbers))
+	for _, rule := range retdata {
+		res = append(res, (&RuleOut{}).FromModel(rule))
+	}
+	if app.cfg.MagicNumbers {
+		for _, rule := range data.MagicNumbers {
+			out := (&RuleOut{}).FromModel(rule)
+			out.Builtin = true
+			res = append(res, out)
+		}
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"smsgate-mock/data"
+	"strings"
+	"time"
+)
+
+type RuleIn struct {
+	Name string `json:"name"`
+	// Priority orders rules, lower is checked first
+	Priority     int    `json:"priority"`
+	PhonePrefix  string `json:"phonePrefix,omitempty"`
+	PhoneSuffix  string `json:"phoneSuffix,omitempty"`
+	PhoneRegex   string `json:"phoneRegex,omitempty"`
+	TextContains string `json:"textContains,omitempty"`
+	TextRegex    string `json:"textRegex,omitempty"`
+	SenderName   string `json:"senderName,omitempty"`
+	MessageType  string `json:"messageType,omitempty"`
+	Login        string `json:"login,omitempty"`
+	// Status is a final status: DELIVERED, UNDELIVERED, EXPIRED or REJECTED
+	Status       string `json:"status"`
+	ErrorCode    int    `json:"errorCode,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	// Delay of the final status like "5s", lifecycle one if empty
+	Delay string `json:"delay,omitempty"`
+}
+
+func (s *RuleIn) ToModel() (*data.Rule, error) {
+	rule := &data.Rule{
+		Name:         s.Name,
+		Priority:     s.Priority,
+		PhonePrefix:  s.PhonePrefix,
+		PhoneSuffix:  s.PhoneSuffix,
+		PhoneRegex:   s.PhoneRegex,
+		TextContains: s.TextContains,
+		TextRegex:    s.TextRegex,
+		SenderName:   s.SenderName,
+		MessageType:  s.MessageType,
+		Login:        s.Login,
+		Status:       strings.ToUpper(s.Status),
+		ErrorCode:    s.ErrorCode,
+		ErrorMessage: s.ErrorMessage,
+	}
+	if len(s.Delay) > 0 {
+		delay, err := time.ParseDuration(s.Delay)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse delay: %v", err)
+		}
+		rule.Delay = delay
+	}
+	return rule, rule.Validate()
+}
+
+type RuleOut struct {
+	RuleUuid uuid.UUID `json:"ruleUuid"`
+	RuleIn
+	// Builtin rules are magic numbers, they can't be changed
+	Builtin bool `json:"builtin"`
+}
+
+func (s *RuleOut) FromModel(src *data.Rule) *RuleOut {
+	s.RuleUuid = src.RuleUuid
+	s.Name = src.Name
+	s.Priority = src.Priority
+	s.PhonePrefix = src.PhonePrefix
+	s.PhoneSuffix = src.PhoneSuffix
+	s.PhoneRegex = src.PhoneRegex
+	s.TextContains = src.TextContains
+	s.TextRegex = src.TextRegex
+	s.SenderName = src.SenderName
+	s.MessageType = src.MessageType
+	s.Login = src.Login
+	s.Status = src.Status
+	s.ErrorCode = src.ErrorCode
+	s.ErrorMessage = src.ErrorMessage
+	if src.Delay > 0 {
+		s.Delay = src.Delay.String()
+	}
+	return s
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"smsgate-mock/data"
+	"smsgate-mock/smsc"
+	"strings"
+)
+
+// senderNameError returns the error of Submit if the sender name can't be used
+func senderNameError(err error) (*smsc.SenderNameError, bool) {
+	var nameErr *smsc.SenderNameError
+	return nameErr, errors.As(err, &nameErr)
+}
+
+// AddSenderName godoc
+// @Summary Register sender name for a sender
+// @Produce json
+// @Param name body SenderNameIn true "New sender name"
+// @Success 201 {object} SenderNameOut
+// @Failure 404 {object} ErrorMessage
+// @Failure 409 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /sender_name [post]
+func (app *App) AddSenderName(c *gin.Context) {
+	var req SenderNameIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	name, err := req.ToModel()
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad sender name: " + err.Error()})
+		return
+	}
+	if err := name.Save(app.db); err != nil {
+		c.Error(fmt.Errorf("can't save sender name: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find sender"})
+		} else if strings.Contains(err.Error(), "already registered") {
+			c.JSON(http.StatusConflict, &ErrorMessage{"Sender name is already registered"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't save sender name due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, (&SenderNameOut{}).FromModel(name))
+}
+
+// EditSenderName godoc
+// @Summary Change approval status or countries of sender name
+// @Produce json
+// @Param senderNameUuid path string true "Sender name ID"
+// @Param name body SenderNameEditIn true "Changes"
+// @Success 200 {object} SenderNameOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /sender_name/{senderNameUuid} [patch]
+func (app *App) EditSenderName(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("senderNameUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse sender name uuid"})
+		return
+	}
+	var req SenderNameEditIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse JSON: %v", err))
+		c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Can't parse request body"})
+		return
+	}
+	name := req.ToModel()
+	if err := name.Edit(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't edit sender name: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested sender name"})
+		} else if strings.Contains(err.Error(), "unknown status") || strings.Contains(err.Error(), "bad country") {
+			c.JSON(http.StatusUnprocessableEntity, &ErrorMessage{"Bad sender name: " + err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't edit sender name due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, (&SenderNameOut{}).FromModel(name))
+}
+
+// GetSenderName godoc
+// @Summary Get sender name
+// @Produce json
+// @Param senderNameUuid path string true "Sender name ID"
+// @Success 200 {object} SenderNameOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /sender_name/{senderNameUuid} [get]
+func (app *App) GetSenderName(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("senderNameUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse sender name uuid"})
+		return
+	}
+	name := &data.SenderName{}
+	if err := name.LoadById(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't load sender name: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested sender name"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't load sender name due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, (&SenderNameOut{}).FromModel(name))
+}
+
+// DeleteSenderName godoc
+// @Summary Delete sender name
+// @Param senderNameUuid path string true "Sender name ID"
+// @Success 204
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /sender_name/{senderNameUuid} [delete]
+func (app *App) DeleteSenderName(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("senderNameUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse sender name uuid"})
+		return
+	}
+	if err := (&data.SenderName{}).Delete(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't delete sender name: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested sender name"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't delete sender name due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// ListSenderNames godoc
+// @Summary List registered sender names
+// @Produce json
+// @Param senderUuid query string false "Sender ID, all senders if empty"
+// @Success 200 {array} SenderNameOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /sender_name [get]
+func (app *App) ListSenderNames(c *gin.Context) {
+	senderUuid := uuid.Nil
+	if len(c.Query("senderUuid")) > 0 {
+		id, err := uuid.Parse(c.Query("senderUuid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse sender uuid"})
+			return
+		}
+		senderUuid = id
+	}
+	retdata, err := (&data.SenderName{}).List(app.db, senderUuid)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list sender names: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list sender names due to internal server error"})
+		return
+	}
+	res := make([]*SenderNameOut, 0, len(retdata))
+	for _, name := range retdata {
+		res = append(res, (&SenderNameOut{}).FromModel(name))
+	}
+	c.JSON(http.StatusOK, res)
+}
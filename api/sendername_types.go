@@ -0,0 +1,87 @@
+package api
+
+import (
+	"github.com/google/uuid"
+	"smsgate-mock/data"
+	"smsgate-mock/smsc"
+	"time"
+)
+
+// sender name error codes
+const (
+	CodeSenderNameNotRegistered = "SENDER_NAME_NOT_REGISTERED"
+	CodeSenderNameNotApproved   = "SENDER_NAME_NOT_APPROVED"
+	CodeSenderNameNotAllowed    = "SENDER_NAME_NOT_ALLOWED"
+)
+
+type SenderNameIn struct {
+	SenderUuid uuid.UUID `json:"senderUuid"`
+	Name       string    `json:"name"`
+	// Status is PENDING, APPROVED or REJECTED, PENDING if empty
+	Status string `json:"status,omitempty"`
+	// Countries are ISO 3166-1 alpha-2 codes of destinations the name is allowed for, empty for any
+	Countries []string `json:"countries,omitempty"`
+}
+
+func (s *SenderNameIn) ToModel() (*data.SenderName, error) {
+	name := &data.SenderName{
+		SenderUuid: s.SenderUuid,
+		Name:       s.Name,
+		Status:     s.Status,
+		Countries:  s.Countries,
+	}
+	if len(name.Status) == 0 {
+		name.Status = data.SenderNamePending
+	}
+	return name, name.Validate()
+}
+
+type SenderNameEditIn struct {
+	// Status and Countries are changed when set
+	Status    string   `json:"status,omitempty"`
+	Countries []string `json:"countries"`
+}
+
+func (s *SenderNameEditIn) ToModel() *data.SenderName {
+	return &data.SenderName{Status: s.Status, Countries: s.Countries}
+}
+
+type SenderNameOut struct {
+	SenderNameUuid uuid.UUID `json:"senderNameUuid"`
+	SenderUuid     uuid.UUID `json:"senderUuid"`
+	Name           string    `json:"name"`
+	Status         string    `json:"status"`
+	Countries      []string  `json:"countries,omitempty"`
+	Create         time.Time `json:"created"`
+	Updated        time.Time `json:"updated"`
+}
+
+func (s *SenderNameOut) FromModel(src *data.SenderName) *SenderNameOut {
+	s.SenderNameUuid = src.SenderNameUuid
+	s.SenderUuid = src.SenderUuid
+	s.Name = src.Name
+	s.Status = src.Status
+	s.Countries = src.Countries
+	s.Create = src.Create
+	s.Updated = src.Updated
+	return s
+}
+
+// SenderNameErrorMessage is returned with 403 when the sender name isn't registered, approved or allowed
+type SenderNameErrorMessage struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+func (s *SenderNameErrorMessage) FromModel(src *smsc.SenderNameError) *SenderNameErrorMessage {
+	s.Error = src.Error()
+	switch src.Reason {
+	case smsc.NameNotApproved:
+		s.Code = CodeSenderNameNotApproved
+	case smsc.NameNotAllowed:
+		s.Code = CodeSenderNameNotAllowed
+	default:
+		s.Code = CodeSenderNameNotRegistered
+	}
+	return s
+}
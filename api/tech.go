@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/ulule/deepcopier"
 	"net/http"
 	"smsgate-mock/data"
 	"strings"
@@ -14,7 +13,7 @@ import (
 // @Summary Create new sender
 // @Produce json
 // @Param sender body SenderIn true "New sender"
-// @Success 201 {object} SenderOut
+// @Success 201 {object} SenderCreatedOut
 // @Failure 422 {object} ErrorMessage
 // @Failure 409 {object} ErrorMessage
 // @Failure 500 {object} ErrorMessage
@@ -36,8 +35,7 @@ func (app *App) AddSender(c *gin.Context) {
 		}
 		return
 	}
-	res := (&SenderOut{}).FromModel(sender)
-	deepcopier.Copy(sender).To(res)
+	res := (&SenderCreatedOut{}).FromModel(sender)
 	c.JSON(http.StatusCreated, res)
 }
 
@@ -92,9 +90,7 @@ func (app *App) EditSender(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, &ErrorMessage{"SenderUuid from URL != SenderUuid from data"})
 		return
 	}
-	sender := &data.Sender{}
-	deepcopier.Copy(req).To(sender)
-	err = sender.Edit(app.db)
+	err = req.ToModel().Edit(app.db, req.CallbackUrl, req.MoCallbackUrl)
 	if err != nil {
 		c.Error(fmt.Errorf("can't edit sender: %v", err))
 		if strings.Contains(err.Error(), "not found") {
@@ -14,20 +14,50 @@ type ErrorMessage struct {
 type SenderIn struct {
 	Login string `json:"login"`
 	Password string `json:"password"`
+	CallbackUrl string `json:"callbackUrl,omitempty"`
+	MoCallbackUrl string `json:"moCallbackUrl,omitempty"`
+	// Secret for webhook signatures, generated if empty
+	Secret string `json:"secret,omitempty"`
+	// Transliterate replaces non GSM-7 characters with lookalikes unless a message overrides it
+	Transliterate *bool `json:"transliterate,omitempty"`
+	// RateLimit is messages per second, RateBurst is a token bucket size; quotas cap messages per UTC day and month
+	RateLimit float64 `json:"rateLimit,omitempty"`
+	RateBurst int `json:"rateBurst,omitempty"`
+	DailyQuota int `json:"dailyQuota,omitempty"`
+	MonthlyQuota int `json:"monthlyQuota,omitempty"`
+	// Prepaid senders can't send messages costing more than their balance
+	Prepaid *bool `json:"prepaid,omitempty"`
 }
 
 func (s *SenderIn) ToModel() *data.Sender {
-	return &data.Sender{Login: s.Login,  Password: s.Password}
+	return &data.Sender{Login: s.Login,  Password: s.Password, CallbackUrl: s.CallbackUrl, MoCallbackUrl: s.MoCallbackUrl, Secret: s.Secret,
+		Transliterate: s.Transliterate, RateLimit: s.RateLimit, RateBurst: s.RateBurst, DailyQuota: s.DailyQuota, MonthlyQuota: s.MonthlyQuota,
+		Prepaid: s.Prepaid}
 }
 
 type SenderEditIn struct {
 	Login string `json:"login"`
 	Password string `json:"password"`
 	SenderUuid uuid.UUID `json:"senderUuid"`
+	// callback urls are changed when set, an empty string removes them
+	CallbackUrl *string `json:"callbackUrl,omitempty"`
+	MoCallbackUrl *string `json:"moCallbackUrl,omitempty"`
+	Secret string `json:"secret,omitempty"`
+	// Transliterate is changed when set
+	Transliterate *bool `json:"transliterate,omitempty"`
+	// limits are changed when not zero, negative ones are removed
+	RateLimit float64 `json:"rateLimit,omitempty"`
+	RateBurst int `json:"rateBurst,omitempty"`
+	DailyQuota int `json:"dailyQuota,omitempty"`
+	MonthlyQuota int `json:"monthlyQuota,omitempty"`
+	// Prepaid is changed when set
+	Prepaid *bool `json:"prepaid,omitempty"`
 }
 
 func (s *SenderEditIn) ToModel() *data.Sender {
-	return &data.Sender{Login: s.Login, Password: s.Password, SenderUuid: s.SenderUuid}
+	return &data.Sender{Login: s.Login, Password: s.Password, SenderUuid: s.SenderUuid, Secret: s.Secret,
+		Transliterate: s.Transliterate, RateLimit: s.RateLimit, RateBurst: s.RateBurst, DailyQuota: s.DailyQuota, MonthlyQuota: s.MonthlyQuota,
+		Prepaid: s.Prepaid}
 }
 
 // output
@@ -35,10 +65,40 @@ func (s *SenderEditIn) ToModel() *data.Sender {
 type SenderOut struct {
 	SenderUuid uuid.UUID `json:"senderUuid"`
 	Login string `json:"login"`
+	CallbackUrl string `json:"callbackUrl,omitempty"`
+	MoCallbackUrl string `json:"moCallbackUrl,omitempty"`
+	Transliterate *bool `json:"transliterate,omitempty"`
+	RateLimit float64 `json:"rateLimit,omitempty"`
+	RateBurst int `json:"rateBurst,omitempty"`
+	DailyQuota int `json:"dailyQuota,omitempty"`
+	MonthlyQuota int `json:"monthlyQuota,omitempty"`
+	Prepaid *bool `json:"prepaid,omitempty"`
+	Balance float64 `json:"balance"`
 }
 
 func (s *SenderOut) FromModel(src *data.Sender) *SenderOut {
 	s.Login = src.Login
 	s.SenderUuid = src.SenderUuid
+	s.CallbackUrl = src.CallbackUrl
+	s.MoCallbackUrl = src.MoCallbackUrl
+	s.Transliterate = src.Transliterate
+	s.RateLimit = src.RateLimit
+	s.RateBurst = src.RateBurst
+	s.DailyQuota = src.DailyQuota
+	s.MonthlyQuota = src.MonthlyQuota
+	s.Prepaid = src.Prepaid
+	s.Balance = src.Balance
+	return s
+}
+
+// SenderCreatedOut is returned only on creation, it's the one place the webhook secret is shown
+type SenderCreatedOut struct {
+	SenderOut
+	Secret string `json:"secret,omitempty"`
+}
+
+func (s *SenderCreatedOut) FromModel(src *data.Sender) *SenderCreatedOut {
+	s.SenderOut.FromModel(src)
+	s.Secret = src.Secret
 	return s
 }
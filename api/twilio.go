@@ -0,0 +1,253 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"net/url"
+	"smsgate-mock/data"
+	"smsgate-mock/smsc"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TwilioAuth checks HTTP Basic credentials against sender's login and password,
+// the login is used as AccountSid
+func (app *App) TwilioAuth(c *gin.Context) {
+	login, password, ok := c.Request.BasicAuth()
+	if !ok {
+		app.twilioUnauthorized(c)
+		return
+	}
+	sender := &data.Sender{}
+	if err := sender.LoadByLogin(app.db, login); err != nil {
+		c.Error(fmt.Errorf("can't load sender: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			app.twilioUnauthorized(c)
+		} else {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, NewTwilioError(http.StatusInternalServerError, 20500, "Internal Server Error"))
+		}
+		return
+	}
+	if sender.Password != password {
+		app.twilioUnauthorized(c)
+		return
+	}
+	if c.Param("AccountSid") != sender.Login {
+		c.AbortWithStatusJSON(http.StatusNotFound, NewTwilioError(http.StatusNotFound, 20404, "The requested resource was not found"))
+		return
+	}
+	c.Set("sender", sender)
+	c.Next()
+}
+
+func (app *App) twilioUnauthorized(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="Twilio API"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, NewTwilioError(http.StatusUnauthorized, 20003, "Authenticate"))
+}
+
+// TwilioSendMessage godoc
+// @Summary Create new SMS, Twilio dialect
+// @Accept x-www-form-urlencoded
+// @Param AccountSid path string true "Sender's login"
+// @Param To formData string true "Phone number"
+// @Param From formData string false "Sender name"
+// @Param Body formData string true "Message text"
+// @Param StatusCallback formData string false "Status callback URL"
+// @Param ValidityPeriod formData int false "Validity period in seconds"
+// @Success 201 {object} TwilioMessageOut
+// @Failure 400 {object} TwilioError
+// @Failure 401 {object} TwilioError
+// @Router /2010-04-01/Accounts/{AccountSid}/Messages.json [post]
+func (app *App) TwilioSendMessage(c *gin.Context) {
+	sender := c.MustGet("sender").(*data.Sender)
+	var req TwilioMessageIn
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(fmt.Errorf("can't parse form: %v", err))
+		c.JSON(http.StatusBadRequest, NewTwilioError(http.StatusBadRequest, 21601, "Can't parse request"))
+		return
+	}
+	if len(req.To) == 0 {
+		c.JSON(http.StatusBadRequest, NewTwilioError(http.StatusBadRequest, 21604, "A 'To' phone number is required."))
+		return
+	}
+	if len(req.From) == 0 && len(req.MessagingServiceSid) == 0 {
+		c.JSON(http.StatusBadRequest, NewTwilioError(http.StatusBadRequest, 21603, "A 'From' phone number is required."))
+		return
+	}
+	if len(req.Body) == 0 {
+		c.JSON(http.StatusBadRequest, NewTwilioError(http.StatusBadRequest, 21602, "Message body is required."))
+		return
+	}
+	msg := req.ToModel()
+	msg.Sender = sender
+	if err := app.smsc.Submit(msg); err != nil {
+		c.Error(fmt.Errorf("can't save message: %v", err))
+		if _, ok := tooLongError(err); ok {
+			c.JSON(http.StatusBadRequest, NewTwilioError(http.StatusBadRequest, 21617, "The concatenated message body exceeds the character limit."))
+			return
+		}
+		if nameErr, ok := senderNameError(err); ok {
+			if nameErr.Reason == smsc.NameNotAllowed {
+				c.JSON(http.StatusBadRequest, NewTwilioError(http.StatusBadRequest, 21408,
+					"Permission to send an SMS has not been enabled for the region indicated by the 'To' number: "+req.To))
+				return
+			}
+			c.JSON(http.StatusBadRequest, NewTwilioError(http.StatusBadRequest, 21212,
+				"The 'From' number "+nameErr.Name+" is not a valid phone number, shortcode, or alphanumeric sender ID."))
+			return
+		}
+		if _, ok := balanceError(err); ok {
+			c.JSON(http.StatusPaymentRequired, NewTwilioError(http.StatusPaymentRequired, 20005,
+				"Account not active: insufficient balance to send this message."))
+			return
+		}
+		if _, ok := limitError(c, err); ok {
+			c.JSON(http.StatusTooManyRequests, NewTwilioError(http.StatusTooManyRequests, 20429, "Too Many Requests"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewTwilioError(http.StatusInternalServerError, 20500, "Internal Server Error"))
+		return
+	}
+	c.JSON(http.StatusCreated, (&TwilioMessageOut{}).FromModel(sender.Login, msg, app.cfg.Currency))
+}
+
+// TwilioGetMessage godoc
+// @Summary Get SMS, Twilio dialect
+// @Param AccountSid path string true "Sender's login"
+// @Param MessageSid path string true "Message SID with .json suffix"
+// @Success 200 {object} TwilioMessageOut
+// @Failure 401 {object} TwilioError
+// @Failure 404 {object} TwilioError
+// @Router /2010-04-01/Accounts/{AccountSid}/Messages/{MessageSid} [get]
+func (app *App) TwilioGetMessage(c *gin.Context) {
+	sender := c.MustGet("sender").(*data.Sender)
+	notFound := NewTwilioError(http.StatusNotFound, 20404, "The requested resource "+c.Request.URL.Path+" was not found")
+	id, err := ParseTwilioSid(c.Param("MessageSid"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, notFound)
+		return
+	}
+	msg := &data.Message{}
+	if err = msg.LoadById(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't load message: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, notFound)
+		} else {
+			c.JSON(http.StatusInternalServerError, NewTwilioError(http.StatusInternalServerError, 20500, "Internal Server Error"))
+		}
+		return
+	}
+	if msg.SenderUuid != sender.SenderUuid {
+		c.JSON(http.StatusNotFound, notFound)
+		return
+	}
+	c.JSON(http.StatusOK, (&TwilioMessageOut{}).FromModel(sender.Login, msg, app.cfg.Currency))
+}
+
+// TwilioListMessages godoc
+// @Summary List SMS, Twilio dialect
+// @Param AccountSid path string true "Sender's login"
+// @Param To query string false "Phone number"
+// @Param From query string false "Sender name"
+// @Param PageSize query int false "Page size, default 50"
+// @Param Page query int false "Page number, default 0"
+// @Success 200 {object} TwilioMessageListOut
+// @Failure 401 {object} TwilioError
+// @Router /2010-04-01/Accounts/{AccountSid}/Messages.json [get]
+func (app *App) TwilioListMessages(c *gin.Context) {
+	sender := c.MustGet("sender").(*data.Sender)
+	to := c.Query("To")
+	from := c.Query("From")
+	pageSize, err := strconv.Atoi(c.DefaultQuery("PageSize", "50"))
+	if err != nil || pageSize <= 0 {
+		c.JSON(http.StatusBadRequest, NewTwilioError(http.StatusBadRequest, 20001, "Invalid PageSize"))
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("Page", "0"))
+	if err != nil || page < 0 {
+		c.JSON(http.StatusBadRequest, NewTwilioError(http.StatusBadRequest, 20001, "Invalid Page"))
+		return
+	}
+	filter := func(msg *data.Message) bool {
+		return msg.SenderUuid == sender.SenderUuid &&
+			(len(to) == 0 || msg.PhoneNumber == to) &&
+			(len(from) == 0 || msg.SenderName == from)
+	}
+	// one extra message shows if there is the next page
+	retdata, err := (&data.Message{}).ListFiltered(app.db, to, filter, pageSize+1, page*pageSize)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list messages: %v", err))
+		c.JSON(http.StatusInternalServerError, NewTwilioError(http.StatusInternalServerError, 20500, "Internal Server Error"))
+		return
+	}
+	res := &TwilioMessageListOut{
+		Messages:     make([]*TwilioMessageOut, 0, pageSize),
+		FirstPageUri: twilioPageUri(sender.Login, to, from, 0, pageSize),
+		Page:         page,
+		PageSize:     pageSize,
+		Start:        page * pageSize,
+		Uri:          twilioPageUri(sender.Login, to, from, page, pageSize),
+	}
+	for i := 0; i < len(retdata) && i < pageSize; i++ {
+		res.Messages = append(res.Messages, (&TwilioMessageOut{}).FromModel(sender.Login, retdata[i], app.cfg.Currency))
+	}
+	res.End = res.Start + len(res.Messages) - 1
+	if len(retdata) > pageSize {
+		next := twilioPageUri(sender.Login, to, from, page+1, pageSize)
+		res.NextPageUri = &next
+	}
+	if page > 0 {
+		previous := twilioPageUri(sender.Login, to, from, page-1, pageSize)
+		res.PreviousPageUri = &previous
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// sendTwilioStatusCallback posts form-encoded status to StatusCallback of the message, signed like Twilio does
+func (app *App) sendTwilioStatusCallback(msg *data.Message) {
+	if len(msg.CallbackUrl) == 0 {
+		return
+	}
+	sender := &data.Sender{}
+	if err := sender.LoadById(app.db, msg.SenderUuid); err != nil {
+		log.Printf("Can't load sender for status callback of %s: %v", msg.MessageUuid, err)
+		return
+	}
+	params := twilioStatusCallbackParams(sender.Login, msg)
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	hook := &data.Webhook{
+		SenderUuid:  msg.SenderUuid,
+		Url:         msg.CallbackUrl,
+		ContentType: "application/x-www-form-urlencoded",
+		Headers:     map[string]string{"X-Twilio-Signature": twilioSignature(sender.Password, msg.CallbackUrl, params)},
+		Body:        form.Encode(),
+	}
+	if err := app.webhooks.Enqueue(hook); err != nil {
+		log.Printf("Can't enqueue status callback of %s to %s: %v", msg.MessageUuid, msg.CallbackUrl, err)
+	}
+}
+
+// twilioSignature is base64 HMAC-SHA1 of the URL followed by sorted POST parameters, keyed with the auth token
+func twilioSignature(authToken, url string, params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	payload := url
+	for _, k := range keys {
+		payload += k + params[k]
+	}
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(payload))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	"net/url"
+	"smsgate-mock/data"
+	"strconv"
+	"strings"
+)
+
+const (
+	TwilioDialect    = "twilio"
+	TwilioApiVersion = "2010-04-01"
+	twilioDateFormat = "Mon, 02 Jan 2006 15:04:05 -0700"
+)
+
+type TwilioError struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	MoreInfo string `json:"more_info"`
+	Status   int    `json:"status"`
+}
+
+func NewTwilioError(status, code int, message string) *TwilioError {
+	return &TwilioError{
+		Code:     code,
+		Message:  message,
+		MoreInfo: fmt.Sprintf("https://www.twilio.com/docs/errors/%d", code),
+		Status:   status,
+	}
+}
+
+type TwilioMessageIn struct {
+	To                  string `form:"To"`
+	From                string `form:"From"`
+	Body                string `form:"Body"`
+	StatusCallback      string `form:"StatusCallback"`
+	MessagingServiceSid string `form:"MessagingServiceSid"`
+	// ValidityPeriod in seconds
+	ValidityPeriod int `form:"ValidityPeriod"`
+	// SmartEncoded replaces Unicode characters with GSM-7 lookalikes
+	SmartEncoded bool `form:"SmartEncoded"`
+}
+
+func (s *TwilioMessageIn) ToModel() *data.Message {
+	msg := &data.Message{
+		SenderName:        s.From,
+		MessageType:       "TEXT",
+		MessageText:       s.Body,
+		ExpirationTimeout: s.ValidityPeriod,
+		PhoneNumber:       s.To,
+		CallbackUrl:       s.StatusCallback,
+		Dialect:           TwilioDialect,
+	}
+	if s.SmartEncoded {
+		msg.Transliterate = &s.SmartEncoded
+	}
+	return msg
+}
+
+type TwilioMessageOut struct {
+	AccountSid          string            `json:"account_sid"`
+	ApiVersion          string            `json:"api_version"`
+	Body                string            `json:"body"`
+	DateCreated         string            `json:"date_created"`
+	DateSent            *string           `json:"date_sent"`
+	DateUpdated         string            `json:"date_updated"`
+	Direction           string            `json:"direction"`
+	ErrorCode           *int              `json:"error_code"`
+	ErrorMessage        *string           `json:"error_message"`
+	From                string            `json:"from"`
+	MessagingServiceSid *string           `json:"messaging_service_sid"`
+	NumMedia            string            `json:"num_media"`
+	NumSegments         string            `json:"num_segments"`
+	Price               *string           `json:"price"`
+	PriceUnit           string            `json:"price_unit"`
+	Sid                 string            `json:"sid"`
+	Status              string            `json:"status"`
+	SubresourceUris     map[string]string `json:"subresource_uris"`
+	To                  string            `json:"to"`
+	Uri                 string            `json:"uri"`
+}
+
+func (s *TwilioMessageOut) FromModel(accountSid string, src *data.Message, currency string) *TwilioMessageOut {
+	sid := TwilioSid(src.MessageUuid)
+	s.AccountSid = accountSid
+	s.ApiVersion = TwilioApiVersion
+	s.Body = src.MessageText
+	s.DateCreated = src.Create.UTC().Format(twilioDateFormat)
+	if !src.Sent.IsZero() {
+		sent := src.Sent.UTC().Format(twilioDateFormat)
+		s.DateSent = &sent
+	}
+	s.DateUpdated = src.Updated.UTC().Format(twilioDateFormat)
+	s.Direction = "outbound-api"
+	if code, message := twilioErrorCode(src); code != 0 {
+		s.ErrorCode = &code
+		s.ErrorMessage = &message
+	}
+	s.From = src.SenderName
+	s.NumMedia = "0"
+	s.NumSegments = strconv.Itoa(src.Segments)
+	if src.Price > 0 {
+		price := fmt.Sprintf("-%.5f", src.Price)
+		s.Price = &price
+	}
+	s.PriceUnit = currency
+	s.Sid = sid
+	s.Status = TwilioStatus(src.Status)
+	s.Uri = fmt.Sprintf("/%s/Accounts/%s/Messages/%s.json", TwilioApiVersion, accountSid, sid)
+	s.SubresourceUris = map[string]string{
+		"media": fmt.Sprintf("/%s/Accounts/%s/Messages/%s/Media.json", TwilioApiVersion, accountSid, sid),
+	}
+	s.To = src.PhoneNumber
+	return s
+}
+
+type TwilioMessageListOut struct {
+	Messages        []*TwilioMessageOut `json:"messages"`
+	End             int                 `json:"end"`
+	FirstPageUri    string              `json:"first_page_uri"`
+	NextPageUri     *string             `json:"next_page_uri"`
+	Page            int                 `json:"page"`
+	PageSize        int                 `json:"page_size"`
+	PreviousPageUri *string             `json:"previous_page_uri"`
+	Start           int                 `json:"start"`
+	Uri             string              `json:"uri"`
+}
+
+// TwilioSid converts message id to Twilio message SID
+func TwilioSid(id uuid.UUID) string {
+	return "SM" + hex.EncodeToString(id[:])
+}
+
+// ParseTwilioSid converts Twilio message SID to message id
+func ParseTwilioSid(sid string) (uuid.UUID, error) {
+	sid = strings.TrimSuffix(sid, ".json")
+	if !strings.HasPrefix(sid, "SM") {
+		return uuid.Nil, fmt.Errorf("bad message sid %s", sid)
+	}
+	bindata, err := hex.DecodeString(sid[2:])
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("bad message sid %s: %v", sid, err)
+	}
+	return uuid.FromBytes(bindata)
+}
+
+// TwilioStatus converts message status to Twilio status vocabulary
+func TwilioStatus(status string) string {
+	switch status {
+	case data.StatusScheduled:
+		return "scheduled"
+	case data.StatusQueued:
+		return "queued"
+	case data.StatusAccepted:
+		return "sending"
+	case data.StatusSent:
+		return "sent"
+	case data.StatusDelivered:
+		return "delivered"
+	case data.StatusUndelivered:
+		return "undelivered"
+	case data.StatusCanceled:
+		return "canceled"
+	}
+	return "failed"
+}
+
+func twilioErrorCode(msg *data.Message) (int, string) {
+	switch msg.Status {
+	case data.StatusUndelivered:
+		return 30003, "Unreachable destination handset"
+	case data.StatusExpired:
+		return 30001, "Queue overflow"
+	case data.StatusRejected:
+		return 30007, "Message filtered"
+	}
+	return 0, ""
+}
+
+// twilioPageUri makes URI of the list page keeping filters
+func twilioPageUri(accountSid string, to, from string, page, pageSize int) string {
+	uri := fmt.Sprintf("/%s/Accounts/%s/Messages.json?PageSize=%d&Page=%d", TwilioApiVersion, accountSid, pageSize, page)
+	if len(to) > 0 {
+		uri += "&To=" + url.QueryEscape(to)
+	}
+	if len(from) > 0 {
+		uri += "&From=" + url.QueryEscape(from)
+	}
+	return uri
+}
+
+func twilioStatusCallbackParams(accountSid string, msg *data.Message) map[string]string {
+	params := map[string]string{
+		"AccountSid":    accountSid,
+		"ApiVersion":    TwilioApiVersion,
+		"From":          msg.SenderName,
+		"MessageSid":    TwilioSid(msg.MessageUuid),
+		"MessageStatus": TwilioStatus(msg.Status),
+		"SmsSid":        TwilioSid(msg.MessageUuid),
+		"SmsStatus":     TwilioStatus(msg.Status),
+		"To":            msg.PhoneNumber,
+	}
+	if code, _ := twilioErrorCode(msg); code != 0 {
+		params["ErrorCode"] = strconv.Itoa(code)
+	}
+	return params
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"regexp"
+	"smsgate-mock/data"
+	"smsgate-mock/phone"
+	"sort"
+	"strings"
+)
+
+// limits of sender IDs
+const (
+	maxAlphaSender   = 11
+	maxNumericSender = 15
+)
+
+var (
+	numericSender = regexp.MustCompile(`^\+?[0-9]+$`)
+	alphaSender   = regexp.MustCompile(`^[A-Za-z0-9 ._&-]+$`)
+)
+
+// bindStrict decodes JSON body into the request, unknown fields and fields of a wrong type are reported
+func bindStrict(c *gin.Context, req interface{}) []FieldError {
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return []FieldError{{Field: "", Code: FieldInvalid, Message: fmt.Sprintf("can't read body: %v", err)}}
+	}
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return []FieldError{{Field: "", Code: FieldInvalid, Message: fmt.Sprintf("body isn't a JSON object: %v", err)}}
+	}
+	fields := jsonFields(req)
+	errs := make([]FieldError, 0)
+	for name, value := range raw {
+		field, ok := fields[name]
+		if !ok {
+			errs = append(errs, FieldError{Field: name, Code: FieldUnknown, Message: "unknown field"})
+			continue
+		}
+		if err := json.Unmarshal(value, field.Addr().Interface()); err != nil {
+			errs = append(errs, FieldError{Field: name, Code: FieldType, Message: fmt.Sprintf("expected %s", field.Type())})
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// jsonFields maps JSON names to settable fields of the struct the pointer points to
+func jsonFields(ptr interface{}) map[string]reflect.Value {
+	ret := make(map[string]reflect.Value)
+	v := reflect.ValueOf(ptr).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		ret[name] = v.Field(i)
+	}
+	return ret
+}
+
+// validateSenderName checks alphanumeric and numeric sender IDs
+func validateSenderName(name string) *FieldError {
+	if name == "" {
+		return &FieldError{Field: "senderName", Code: FieldRequired, Message: "sender name is required"}
+	}
+	if numericSender.MatchString(name) {
+		if len(strings.TrimPrefix(name, "+")) > maxNumericSender {
+			return &FieldError{Field: "senderName", Code: FieldTooLong,
+				Message: fmt.Sprintf("numeric sender ID can't have more than %d digits", maxNumericSender)}
+		}
+		return nil
+	}
+	if !alphaSender.MatchString(name) {
+		return &FieldError{Field: "senderName", Code: FieldInvalid,
+			Message: "alphanumeric sender ID can only have latin letters, digits, spaces and . _ & -"}
+	}
+	if len(name) > maxAlphaSender {
+		return &FieldError{Field: "senderName", Code: FieldTooLong,
+			Message: fmt.Sprintf("alphanumeric sender ID can't be longer than %d characters", maxAlphaSender)}
+	}
+	return nil
+}
+
+func validateMessageType(messageType string) *FieldError {
+	if messageType == "" {
+		return &FieldError{Field: "messageType", Code: FieldRequired, Message: "message type is required"}
+	}
+	for _, known := range data.MessageTypes {
+		if messageType == known {
+			return nil
+		}
+	}
+	return &FieldError{Field: "messageType", Code: FieldInvalid,
+		Message: fmt.Sprintf("message type must be one of %s", strings.Join(data.MessageTypes, ", "))}
+}
+
+// mergeFieldErrors adds errors of the fields which aren't reported yet
+func mergeFieldErrors(errs []FieldError, more []FieldError) []FieldError {
+	reported := make(map[string]bool)
+	for _, err := range errs {
+		reported[err.Field] = true
+	}
+	for _, err := range more {
+		if !reported[err.Field] {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// validationFailed responds with all field errors
+func validationFailed(c *gin.Context, errs []FieldError) {
+	c.Error(fmt.Errorf("validation failed: %v", errs))
+	c.JSON(http.StatusUnprocessableEntity, &ValidationErrorMessage{Error: "Validation failed", Fields: errs})
+}
+
+// Validate checks the message in strict mode and normalizes its phone number to E.164
+func (s *MessageIn) Validate(country string) []FieldError {
+	errs := make([]FieldError, 0)
+	if s.Login == "" {
+		errs = append(errs, FieldError{Field: "login", Code: FieldRequired, Message: "login is required"})
+	}
+	if s.Password == "" {
+		errs = append(errs, FieldError{Field: "password", Code: FieldRequired, Message: "password is required"})
+	}
+	if err := validateSenderName(s.SenderName); err != nil {
+		errs = append(errs, *err)
+	}
+	if err := validateMessageType(s.MessageType); err != nil {
+		errs = append(errs, *err)
+	}
+	if s.MessageText == "" {
+		errs = append(errs, FieldError{Field: "messageText", Code: FieldRequired, Message: "message text is required"})
+	}
+	if s.ExpirationTimeout < 0 {
+		errs = append(errs, FieldError{Field: "expirationTimeout", Code: FieldInvalid, Message: "expiration timeout can't be negative"})
+	}
+	if s.PhoneNumber == "" {
+		errs = append(errs, FieldError{Field: "phoneNumber", Code: FieldRequired, Message: "phone number is required"})
+	} else if number, err := phone.Normalize(s.PhoneNumber, country); err != nil {
+		errs = append(errs, FieldError{Field: "phoneNumber", Code: FieldInvalid, Message: err.Error()})
+	} else {
+		s.PhoneNumber = number
+	}
+	return errs
+}
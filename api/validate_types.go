@@ -0,0 +1,23 @@
+package api
+
+// field error codes
+const (
+	FieldRequired = "REQUIRED"
+	FieldUnknown  = "UNKNOWN_FIELD"
+	FieldType     = "INVALID_TYPE"
+	FieldInvalid  = "INVALID_VALUE"
+	FieldTooLong  = "TOO_LONG"
+)
+
+// FieldError describes one invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorMessage is returned in strict validation mode with all invalid fields
+type ValidationErrorMessage struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields"`
+}
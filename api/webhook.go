@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"smsgate-mock/data"
+	"strings"
+)
+
+// ListWebhooks godoc
+// @Summary List webhooks waiting for delivery
+// @Param limit query string false "Limit, default 10"
+// @Param offset query string false "Offset, default 0"
+// @Success 200 {array} WebhookOut
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /webhook [get]
+func (app *App) ListWebhooks(c *gin.Context) {
+	limit, offset, ok := parseLimitOffset(c)
+	if !ok {
+		return
+	}
+	retdata, err := (&data.Webhook{}).List(app.db, limit, offset)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list webhooks: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list webhooks due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, webhooksOut(retdata))
+}
+
+// ListDeadWebhooks godoc
+// @Summary List webhooks failed after all attempts
+// @Param limit query string false "Limit, default 10"
+// @Param offset query string false "Offset, default 0"
+// @Success 200 {array} WebhookOut
+// @Failure 422 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /webhook/dead [get]
+func (app *App) ListDeadWebhooks(c *gin.Context) {
+	limit, offset, ok := parseLimitOffset(c)
+	if !ok {
+		return
+	}
+	retdata, err := (&data.Webhook{}).ListDead(app.db, limit, offset)
+	if err != nil {
+		c.Error(fmt.Errorf("can't list dead webhooks: %v", err))
+		c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't list webhooks due to internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, webhooksOut(retdata))
+}
+
+// ReplayWebhook godoc
+// @Summary Move dead webhook back to the delivery queue
+// @Param webhookUuid path string true "Webhook ID"
+// @Success 200 {object} WebhookOut
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /webhook/dead/{webhookUuid}/replay [post]
+func (app *App) ReplayWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("webhookUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse webhook uuid"})
+		return
+	}
+	hook := &data.Webhook{}
+	if err = hook.Replay(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't replay webhook: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested webhook"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't replay webhook due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, (&WebhookOut{}).FromModel(hook))
+}
+
+// DeleteDeadWebhook godoc
+// @Summary Delete dead webhook
+// @Param webhookUuid path string true "Webhook ID"
+// @Success 204
+// @Failure 400 {object} ErrorMessage
+// @Failure 404 {object} ErrorMessage
+// @Failure 500 {object} ErrorMessage
+// @Router /webhook/dead/{webhookUuid} [delete]
+func (app *App) DeleteDeadWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("webhookUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &ErrorMessage{"Can't parse webhook uuid"})
+		return
+	}
+	if err = (&data.Webhook{}).DeleteDead(app.db, id); err != nil {
+		c.Error(fmt.Errorf("can't delete dead webhook: %v", err))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, &ErrorMessage{"Can't find requested webhook"})
+		} else {
+			c.JSON(http.StatusInternalServerError, &ErrorMessage{"Can't delete webhook due to internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+func webhooksOut(retdata []*data.Webhook) []*WebhookOut {
+	res := make([]*WebhookOut, len(retdata))
+	for i := 0; i < len(retdata); i++ {
+		res[i] = (&WebhookOut{}).FromModel(retdata[i])
+	}
+	return res
+}
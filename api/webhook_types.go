@@ -0,0 +1,34 @@
+package api
+
+import (
+	"github.com/google/uuid"
+	"smsgate-mock/data"
+	"time"
+)
+
+type WebhookOut struct {
+	WebhookUuid uuid.UUID `json:"webhookUuid"`
+	SenderUuid  uuid.UUID `json:"senderUuid"`
+	Method      string    `json:"method"`
+	Url         string    `json:"url"`
+	Body        string    `json:"body,omitempty"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	LastError   string    `json:"lastError,omitempty"`
+	Create      time.Time `json:"created"`
+	Died        time.Time `json:"died"`
+}
+
+func (s *WebhookOut) FromModel(src *data.Webhook) *WebhookOut {
+	s.WebhookUuid = src.WebhookUuid
+	s.SenderUuid = src.SenderUuid
+	s.Method = src.Method
+	s.Url = src.Url
+	s.Body = src.Body
+	s.Attempts = src.Attempts
+	s.NextAttempt = src.NextAttempt
+	s.LastError = src.LastError
+	s.Create = src.Create
+	s.Died = src.Died
+	return s
+}
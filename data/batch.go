@@ -0,0 +1,104 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"time"
+)
+
+// Batch is a group of messages submitted in one request
+type Batch struct {
+	BatchUuid  uuid.UUID
+	SenderUuid uuid.UUID
+	// MessageUuids are the accepted messages, Total counts the rejected ones too
+	MessageUuids []uuid.UUID
+	Total        int
+	Create       time.Time
+}
+
+func (s *Batch) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *Batch) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Save saves the batch with its messages in one transaction debiting their prices,
+// charged tells for every message whether it was saved: prepaid senders can run out of money in the middle
+func (s *Batch) Save(db *bbolt.DB, msgs []*Message) (charged []bool, err error) {
+	s.BatchUuid = uuid.New()
+	s.Create = time.Now()
+	s.MessageUuids = make([]uuid.UUID, 0, len(msgs))
+	charged = make([]bool, len(msgs))
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for i, msg := range msgs {
+			msg.BatchUuid = s.BatchUuid
+			msg.prepare()
+			ok, err := msg.insertCharged(tx)
+			if err != nil {
+				return err
+			}
+			charged[i] = ok
+			if ok {
+				s.MessageUuids = append(s.MessageUuids, msg.MessageUuid)
+			}
+		}
+		if err := tx.Bucket([]byte(BucketBatches)).Put(s.BatchUuid[:], s.Bytes()); err != nil {
+			return fmt.Errorf("can't save batch: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return charged, nil
+}
+
+func (s *Batch) LoadById(db *bbolt.DB, id uuid.UUID) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		bindata := tx.Bucket([]byte(BucketBatches)).Get(id[:])
+		if bindata == nil {
+			return fmt.Errorf("batch not found: %s", id.String())
+		}
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse batch data: %v %s", err, string(bindata))
+		}
+		return nil
+	})
+}
+
+// Statuses counts the messages of the batch by status, deleted messages aren't counted
+func (s *Batch) Statuses(db *bbolt.DB) (map[string]int, error) {
+	return countStatuses(db, s.MessageUuids)
+}
+
+// countStatuses counts the messages by status skipping deleted ones
+func countStatuses(db *bbolt.DB, ids []uuid.UUID) (map[string]int, error) {
+	ret := make(map[string]int)
+	err := db.View(func(tx *bbolt.Tx) error {
+		bucketMessages := tx.Bucket([]byte(BucketMessages))
+		for _, id := range ids {
+			bindata := bucketMessages.Get(id[:])
+			if bindata == nil {
+				continue
+			}
+			msg := &Message{}
+			if err := msg.FromBytes(bindata); err != nil {
+				return fmt.Errorf("can't parse message: %v, %s", err, string(bindata))
+			}
+			ret[msg.Status] += 1
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
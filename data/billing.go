@@ -0,0 +1,290 @@
+package data
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ledger entry types
+const (
+	LedgerDebit  = "DEBIT"
+	LedgerRefund = "REFUND"
+	LedgerTopUp  = "TOPUP"
+)
+
+// RoundMoney rounds the amount to 5 decimal places like provider invoices do
+func RoundMoney(amount float64) float64 {
+	return math.Round(amount*1e5) / 1e5
+}
+
+// Price is a price of one segment of messages to a country or to numbers with a prefix
+type Price struct {
+	PriceUuid uuid.UUID
+	// Country is ISO 3166-1 alpha-2 code, Prefix is international number digits without +,
+	// the longest matching prefix wins over the country
+	Country    string `json:",omitempty"`
+	Prefix     string `json:",omitempty"`
+	PerSegment float64
+	Create     time.Time
+}
+
+func (s *Price) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *Price) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate checks the price and normalizes the country and the prefix
+func (s *Price) Validate() error {
+	s.Country = strings.ToUpper(s.Country)
+	s.Prefix = strings.TrimPrefix(s.Prefix, "+")
+	if len(s.Country) == 0 && len(s.Prefix) == 0 {
+		return fmt.Errorf("country or prefix is required")
+	}
+	if len(s.Country) > 0 && len(s.Country) != 2 {
+		return fmt.Errorf("bad country code %s", s.Country)
+	}
+	for _, r := range s.Prefix {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("bad prefix %s", s.Prefix)
+		}
+	}
+	if s.PerSegment < 0 {
+		return fmt.Errorf("price can't be negative")
+	}
+	return nil
+}
+
+func (s *Price) Save(db *bbolt.DB) error {
+	s.PriceUuid = uuid.New()
+	s.Create = time.Now()
+	return db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket([]byte(BucketPrices)).Put(s.PriceUuid[:], s.Bytes()); err != nil {
+			return fmt.Errorf("can't save price: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Price) LoadById(db *bbolt.DB, id uuid.UUID) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		bindata := tx.Bucket([]byte(BucketPrices)).Get(id[:])
+		if bindata == nil {
+			return fmt.Errorf("price not found: %s", id.String())
+		}
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse price data: %v %s", err, string(bindata))
+		}
+		return nil
+	})
+}
+
+func (s *Price) Delete(db *bbolt.DB, id uuid.UUID) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketPrices := tx.Bucket([]byte(BucketPrices))
+		if bucketPrices.Get(id[:]) == nil {
+			return fmt.Errorf("price not found")
+		}
+		if err := bucketPrices.Delete(id[:]); err != nil {
+			return fmt.Errorf("can't delete price: %v", err)
+		}
+		return nil
+	})
+}
+
+// List returns all prices sorted by country and prefix
+func (s *Price) List(db *bbolt.DB) ([]*Price, error) {
+	ret := make([]*Price, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(BucketPrices)).ForEach(func(k, v []byte) error {
+			price := &Price{}
+			if err := price.FromBytes(v); err != nil {
+				return fmt.Errorf("can't parse price: %v, %s", err, string(v))
+			}
+			ret = append(ret, price)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Country != ret[j].Country {
+			return ret[i].Country < ret[j].Country
+		}
+		return ret[i].Prefix < ret[j].Prefix
+	})
+	return ret, nil
+}
+
+// Find returns the price with the longest prefix of the digits, then the price of the country, or nil
+func (s *Price) Find(db *bbolt.DB, digits, country string) (*Price, error) {
+	prices, err := s.List(db)
+	if err != nil {
+		return nil, err
+	}
+	var ret *Price
+	for _, price := range prices {
+		if len(price.Prefix) > 0 {
+			if strings.HasPrefix(digits, price.Prefix) && (ret == nil || len(price.Prefix) > len(ret.Prefix)) {
+				ret = price
+			}
+		} else if ret == nil && len(country) > 0 && price.Country == country {
+			ret = price
+		}
+	}
+	return ret, nil
+}
+
+// LedgerEntry is a change of a sender's balance
+type LedgerEntry struct {
+	EntryUuid   uuid.UUID
+	SenderUuid  uuid.UUID
+	MessageUuid uuid.UUID
+	Type        string
+	// Amount is negative for debits, Balance is the sender's balance after the entry
+	Amount      float64
+	Balance     float64
+	Description string
+	Create      time.Time
+}
+
+func (s *LedgerEntry) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *LedgerEntry) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Key orders entries by sender and time
+func (s *LedgerEntry) Key() []byte {
+	key := make([]byte, 0, 40)
+	key = append(key, s.SenderUuid[:]...)
+	ts := make([]byte, 8)
+	binary.BigEndian.PutUint64(ts, uint64(s.Create.UnixNano()))
+	key = append(key, ts...)
+	return append(key, s.EntryUuid[:]...)
+}
+
+// post changes the sender's balance by the amount and records the entry,
+// applied is false if the balance would become negative and prepaid is set
+func (s *LedgerEntry) post(tx *bbolt.Tx, prepaid bool) (applied bool, err error) {
+	bucketSenders := tx.Bucket([]byte(BucketSenders))
+	bindata := bucketSenders.Get(s.SenderUuid[:])
+	if bindata == nil {
+		return false, fmt.Errorf("sender not found")
+	}
+	sender := &Sender{}
+	if err := sender.FromBytes(bindata); err != nil {
+		return false, fmt.Errorf("can't parse sender data: %v, %s", err, string(bindata))
+	}
+	s.Amount = RoundMoney(s.Amount)
+	s.Balance = RoundMoney(sender.Balance + s.Amount)
+	if prepaid && s.Amount < 0 && s.Balance < 0 {
+		return false, nil
+	}
+	sender.Balance = s.Balance
+	if err := bucketSenders.Put(s.SenderUuid[:], sender.Bytes()); err != nil {
+		return false, fmt.Errorf("can't save sender: %v", err)
+	}
+	s.EntryUuid = uuid.New()
+	s.Create = time.Now()
+	if err := tx.Bucket([]byte(BucketLedger)).Put(s.Key(), s.Bytes()); err != nil {
+		return false, fmt.Errorf("can't save ledger entry: %v", err)
+	}
+	return true, nil
+}
+
+// TopUp adds the amount to the sender's balance
+func (s *LedgerEntry) TopUp(db *bbolt.DB, senderUuid uuid.UUID, amount float64, description string) error {
+	if amount <= 0 {
+		return fmt.Errorf("top-up amount must be positive")
+	}
+	*s = LedgerEntry{SenderUuid: senderUuid, Type: LedgerTopUp, Amount: amount, Description: description}
+	return db.Update(func(tx *bbolt.Tx) error {
+		_, err := s.post(tx, false)
+		return err
+	})
+}
+
+// Refund returns the price of the failed message to its sender
+func (s *LedgerEntry) Refund(db *bbolt.DB, msg *Message) error {
+	*s = LedgerEntry{SenderUuid: msg.SenderUuid, MessageUuid: msg.MessageUuid, Type: LedgerRefund, Amount: msg.Price,
+		Description: fmt.Sprintf("refund of message to %s: %s", msg.PhoneNumber, msg.Status)}
+	return db.Update(func(tx *bbolt.Tx) error {
+		_, err := s.post(tx, false)
+		return err
+	})
+}
+
+// List returns the sender's entries in chronological order
+func (s *LedgerEntry) List(db *bbolt.DB, senderUuid uuid.UUID, limit, offset int) ([]*LedgerEntry, error) {
+	ret := make([]*LedgerEntry, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		iterator := tx.Bucket([]byte(BucketLedger)).Cursor()
+		if limit == 0 {
+			limit = 10
+		}
+		limit += offset
+		prefix := senderUuid[:]
+		i := 0
+		for k, v := iterator.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix) && i < limit; k, v = iterator.Next() {
+			i += 1
+			if i <= offset {
+				continue
+			}
+			entry := &LedgerEntry{}
+			if err := entry.FromBytes(v); err != nil {
+				return fmt.Errorf("can't parse ledger entry: %v, %s", err, string(v))
+			}
+			ret = append(ret, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// SaveCharged saves the new message debiting its price from the sender's balance in the same transaction,
+// charged is false and the message isn't saved if the sender is prepaid and the balance is insufficient
+func (s *Message) SaveCharged(db *bbolt.DB) (charged bool, err error) {
+	s.prepare()
+	err = db.Update(func(tx *bbolt.Tx) error {
+		charged, err = s.insertCharged(tx)
+		return err
+	})
+	return charged, err
+}
+
+// insertCharged puts the prepared message debiting its price, the message isn't put if it isn't charged
+func (s *Message) insertCharged(tx *bbolt.Tx) (charged bool, err error) {
+	if s.Price != 0 {
+		entry := &LedgerEntry{SenderUuid: s.SenderUuid, MessageUuid: s.MessageUuid, Type: LedgerDebit, Amount: -s.Price,
+			Description: fmt.Sprintf("%d segments to %s", s.Segments, s.PhoneNumber)}
+		if charged, err = entry.post(tx, s.Sender.IsPrepaid()); err != nil || !charged {
+			return charged, err
+		}
+	}
+	return true, s.insert(tx)
+}
@@ -0,0 +1,286 @@
+package data
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"sort"
+	"text/template"
+	"time"
+)
+
+// statuses of campaigns
+const (
+	CampaignRunning   = "RUNNING"
+	CampaignPaused    = "PAUSED"
+	CampaignAborted   = "ABORTED"
+	CampaignCompleted = "COMPLETED"
+)
+
+// CampaignRecipient is a phone number with values for the campaign template
+type CampaignRecipient struct {
+	PhoneNumber string
+	Values      map[string]string `json:",omitempty"`
+}
+
+// Campaign sends a templated text to its recipients in the background at Speed messages per second
+type Campaign struct {
+	CampaignUuid uuid.UUID
+	SenderUuid   uuid.UUID
+	Name         string
+	SenderName   string
+	MessageType  string
+	// Template is a text/template rendered with the recipient's values and .phoneNumber
+	Template string
+	// Speed is messages per second, zero sends as fast as the center ticks
+	Speed float64
+	// Recipients are given on creation only, they're stored apart from the campaign; Total is their number
+	Recipients []CampaignRecipient `json:"-"`
+	Total      int
+	// Next is the index of the next recipient, NextAt is the time it's due
+	Next   int
+	NextAt time.Time
+	// Rejected counts recipients the center didn't accept
+	Rejected int
+	// LastError is the reason of the last rejection
+	LastError string `json:",omitempty"`
+	Status    string
+	Create    time.Time
+	Updated   time.Time
+	Done      time.Time
+}
+
+// CampaignProgress counts the campaign's recipients by the state of their messages
+type CampaignProgress struct {
+	// Pending recipients have no message yet, Skipped ones won't get it as the campaign was aborted
+	Pending   int
+	Skipped   int
+	Queued    int
+	Sent      int
+	Delivered int
+	// Failed counts failed, expired, canceled and rejected messages
+	Failed   int
+	Statuses map[string]int
+}
+
+func (s *Campaign) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *Campaign) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// campaignKey is a key of the campaign's recipient and its message, they're kept in the recipients' order
+func campaignKey(id uuid.UUID, i int) []byte {
+	key := make([]byte, len(id)+8)
+	copy(key, id[:])
+	binary.BigEndian.PutUint64(key[len(id):], uint64(i))
+	return key
+}
+
+func (s *Campaign) parse() (*template.Template, error) {
+	return template.New(s.Name).Option("missingkey=error").Parse(s.Template)
+}
+
+func (s *Campaign) Validate() error {
+	if len(s.Name) == 0 {
+		return fmt.Errorf("name is empty")
+	}
+	if len(s.Template) == 0 {
+		return fmt.Errorf("template is empty")
+	}
+	if _, err := s.parse(); err != nil {
+		return fmt.Errorf("can't parse template: %v", err)
+	}
+	if s.Speed < 0 {
+		return fmt.Errorf("speed can't be negative")
+	}
+	if len(s.Recipients) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+	for i, recipient := range s.Recipients {
+		if len(recipient.PhoneNumber) == 0 {
+			return fmt.Errorf("recipient %d has no phone number", i)
+		}
+	}
+	return nil
+}
+
+// Render makes the text for the recipient
+func (s *Campaign) Render(recipient *CampaignRecipient) (string, error) {
+	tpl, err := s.parse()
+	if err != nil {
+		return "", err
+	}
+	values := map[string]string{"phoneNumber": recipient.PhoneNumber}
+	for k, v := range recipient.Values {
+		values[k] = v
+	}
+	var text bytes.Buffer
+	if err := tpl.Execute(&text, values); err != nil {
+		return "", fmt.Errorf("can't render text: %v", err)
+	}
+	return text.String(), nil
+}
+
+// Save creates the campaign with its recipients, it starts right away
+func (s *Campaign) Save(db *bbolt.DB) error {
+	s.CampaignUuid = uuid.New()
+	s.Create = time.Now()
+	s.Updated = s.Create
+	s.NextAt = s.Create
+	s.Status = CampaignRunning
+	s.Total = len(s.Recipients)
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketRecipients := tx.Bucket([]byte(BucketCampaignRecipients))
+		for i := range s.Recipients {
+			bindata, _ := json.Marshal(&s.Recipients[i])
+			if err := bucketRecipients.Put(campaignKey(s.CampaignUuid, i), bindata); err != nil {
+				return fmt.Errorf("can't save campaign recipient: %v", err)
+			}
+		}
+		return s.put(tx)
+	})
+}
+
+// Update saves the changed campaign
+func (s *Campaign) Update(db *bbolt.DB) error {
+	return db.Update(s.put)
+}
+
+// UpdateProgress saves the changed campaign with messages submitted to its recipients by their index
+func (s *Campaign) UpdateProgress(db *bbolt.DB, messages map[int]uuid.UUID) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketMessages := tx.Bucket([]byte(BucketCampaignMessages))
+		for i, id := range messages {
+			if err := bucketMessages.Put(campaignKey(s.CampaignUuid, i), id[:]); err != nil {
+				return fmt.Errorf("can't save campaign message: %v", err)
+			}
+		}
+		return s.put(tx)
+	})
+}
+
+func (s *Campaign) put(tx *bbolt.Tx) error {
+	if err := tx.Bucket([]byte(BucketCampaigns)).Put(s.CampaignUuid[:], s.Bytes()); err != nil {
+		return fmt.Errorf("can't save campaign: %v", err)
+	}
+	return nil
+}
+
+// LoadRecipients returns up to limit recipients starting from the index
+func (s *Campaign) LoadRecipients(db *bbolt.DB, from, limit int) ([]CampaignRecipient, error) {
+	ret := make([]CampaignRecipient, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		iterator := tx.Bucket([]byte(BucketCampaignRecipients)).Cursor()
+		for k, v := iterator.Seek(campaignKey(s.CampaignUuid, from)); k != nil && bytes.HasPrefix(k, s.CampaignUuid[:]) && len(ret) < limit; k, v = iterator.Next() {
+			recipient := CampaignRecipient{}
+			if err := json.Unmarshal(v, &recipient); err != nil {
+				return fmt.Errorf("can't parse campaign recipient: %v, %s", err, string(v))
+			}
+			ret = append(ret, recipient)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// MessageUuids returns the messages submitted by the campaign
+func (s *Campaign) MessageUuids(db *bbolt.DB) ([]uuid.UUID, error) {
+	ret := make([]uuid.UUID, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		iterator := tx.Bucket([]byte(BucketCampaignMessages)).Cursor()
+		for k, v := iterator.Seek(s.CampaignUuid[:]); k != nil && bytes.HasPrefix(k, s.CampaignUuid[:]); k, v = iterator.Next() {
+			id, err := uuid.FromBytes(v)
+			if err != nil {
+				return fmt.Errorf("can't parse campaign message id: %v", err)
+			}
+			ret = append(ret, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (s *Campaign) LoadById(db *bbolt.DB, id uuid.UUID) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		bindata := tx.Bucket([]byte(BucketCampaigns)).Get(id[:])
+		if bindata == nil {
+			return fmt.Errorf("campaign not found: %s", id.String())
+		}
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse campaign data: %v %s", err, string(bindata))
+		}
+		return nil
+	})
+}
+
+// List returns campaigns in the status or all of them if it's empty, newest first
+func (s *Campaign) List(db *bbolt.DB, status string) ([]*Campaign, error) {
+	ret := make([]*Campaign, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(BucketCampaigns)).ForEach(func(k, v []byte) error {
+			campaign := &Campaign{}
+			if err := campaign.FromBytes(v); err != nil {
+				return fmt.Errorf("can't parse campaign: %v, %s", err, string(v))
+			}
+			if len(status) == 0 || campaign.Status == status {
+				ret = append(ret, campaign)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Create.After(ret[j].Create)
+	})
+	return ret, nil
+}
+
+// Progress counts the campaign's messages by their current status
+func (s *Campaign) Progress(db *bbolt.DB) (*CampaignProgress, error) {
+	ids, err := s.MessageUuids(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := countStatuses(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	ret := &CampaignProgress{Failed: s.Rejected, Statuses: statuses}
+	switch s.Status {
+	case CampaignRunning, CampaignPaused:
+		ret.Pending = s.Total - s.Next
+	case CampaignAborted:
+		ret.Skipped = s.Total - s.Next
+	}
+	for status, count := range statuses {
+		switch status {
+		case StatusScheduled, StatusQueued, StatusAccepted:
+			ret.Queued += count
+		case StatusSent:
+			ret.Sent += count
+		case StatusDelivered:
+			ret.Delivered += count
+		default:
+			ret.Failed += count
+		}
+	}
+	return ret, nil
+}
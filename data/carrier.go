@@ -0,0 +1,125 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.etcd.io/bbolt"
+	"time"
+)
+
+// Outage is a window when a carrier doesn't deliver messages
+type Outage struct {
+	Start time.Time
+	End   time.Time
+}
+
+// CarrierProfile changes delivery of messages to a carrier
+type CarrierProfile struct {
+	// CarrierId is MCC and MNC of the carrier
+	CarrierId string
+	// Latency is added to the delivery of every message
+	Latency time.Duration
+	// FailureRate is a percentage of messages which become UNDELIVERED with ErrorCode and ErrorMessage,
+	// messages submitted during Outages fail the same way
+	FailureRate  float64
+	ErrorCode    int
+	ErrorMessage string
+	Outages      []Outage `json:",omitempty"`
+	Updated      time.Time
+}
+
+func (s *CarrierProfile) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *CarrierProfile) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *CarrierProfile) Validate() error {
+	if s.Latency < 0 {
+		return fmt.Errorf("latency can't be negative")
+	}
+	if s.FailureRate < 0 || s.FailureRate > 100 {
+		return fmt.Errorf("failure rate must be from 0 to 100")
+	}
+	for _, outage := range s.Outages {
+		if !outage.End.After(outage.Start) {
+			return fmt.Errorf("outage ends before it starts")
+		}
+	}
+	return nil
+}
+
+// InOutage reports whether the carrier is down at the time
+func (s *CarrierProfile) InOutage(now time.Time) bool {
+	for _, outage := range s.Outages {
+		if !now.Before(outage.Start) && now.Before(outage.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// Save creates or replaces the profile of the carrier
+func (s *CarrierProfile) Save(db *bbolt.DB) error {
+	s.Updated = time.Now()
+	return db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket([]byte(BucketCarrierProfiles)).Put([]byte(s.CarrierId), s.Bytes()); err != nil {
+			return fmt.Errorf("can't save carrier profile: %v", err)
+		}
+		return nil
+	})
+}
+
+// Load loads the profile of the carrier, found is false if it has none
+func (s *CarrierProfile) Load(db *bbolt.DB, carrierId string) (found bool, err error) {
+	err = db.View(func(tx *bbolt.Tx) error {
+		bindata := tx.Bucket([]byte(BucketCarrierProfiles)).Get([]byte(carrierId))
+		if bindata == nil {
+			return nil
+		}
+		found = true
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse carrier profile data: %v %s", err, string(bindata))
+		}
+		return nil
+	})
+	return found, err
+}
+
+func (s *CarrierProfile) Delete(db *bbolt.DB, carrierId string) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketProfiles := tx.Bucket([]byte(BucketCarrierProfiles))
+		if bucketProfiles.Get([]byte(carrierId)) == nil {
+			return fmt.Errorf("carrier profile not found")
+		}
+		if err := bucketProfiles.Delete([]byte(carrierId)); err != nil {
+			return fmt.Errorf("can't delete carrier profile: %v", err)
+		}
+		return nil
+	})
+}
+
+// List returns profiles of all carriers by carrier id
+func (s *CarrierProfile) List(db *bbolt.DB) (map[string]*CarrierProfile, error) {
+	ret := make(map[string]*CarrierProfile)
+	err := db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(BucketCarrierProfiles)).ForEach(func(k, v []byte) error {
+			profile := &CarrierProfile{}
+			if err := profile.FromBytes(v); err != nil {
+				return fmt.Errorf("can't parse carrier profile: %v, %s", err, string(v))
+			}
+			ret[profile.CarrierId] = profile
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -11,25 +11,70 @@ const (
 	BucketSendersByLogin = "SendersByLogin"
 	BucketMessages = "Messages"
 	BucketMessageIndex = "MessageIndex"
+	BucketMessagePending = "MessagePending"
+	BucketWebhooks = "Webhooks"
+	BucketWebhookQueue = "WebhookQueue"
+	BucketWebhookDeadLetters = "WebhookDeadLetters"
+	BucketInbound = "Inbound"
+	BucketInboundIndex = "InboundIndex"
+	BucketRules = "Rules"
+	BucketFaults = "Faults"
+	BucketAdmin = "Admin"
+	BucketUsage = "Usage"
+	BucketSenderNames = "SenderNames"
+	BucketPrices = "Prices"
+	BucketLedger = "Ledger"
+	BucketCarrierProfiles = "CarrierProfiles"
+	BucketSubscribers = "Subscribers"
+	BucketHandsets = "Handsets"
+	BucketHeldMessages = "HeldMessages"
+	BucketBatches = "Batches"
+	BucketCampaigns = "Campaigns"
+	BucketCampaignRecipients = "CampaignRecipients"
+	BucketCampaignMessages = "CampaignMessages"
 )
 
+var buckets = []string{
+	BucketSenders,
+	BucketSendersByLogin,
+	BucketMessages,
+	BucketMessageIndex,
+	BucketMessagePending,
+	BucketWebhooks,
+	BucketWebhookQueue,
+	BucketWebhookDeadLetters,
+	BucketInbound,
+	BucketInboundIndex,
+	BucketRules,
+	BucketFaults,
+	BucketAdmin,
+	BucketUsage,
+	BucketSenderNames,
+	BucketPrices,
+	BucketLedger,
+	BucketCarrierProfiles,
+	BucketSubscribers,
+	BucketHandsets,
+	BucketHeldMessages,
+	BucketBatches,
+	BucketCampaigns,
+	BucketCampaignRecipients,
+	BucketCampaignMessages,
+}
+
 func InitBuckets(db *bbolt.DB) {
 	err := db.Update(func(tx *bbolt.Tx) error {
-		if _, err := tx.CreateBucketIfNotExists([]byte(BucketSenders)); err != nil {
-			return fmt.Errorf("can't create bucket Senders: %v", err)
-		}
-		if _, err := tx.CreateBucketIfNotExists([]byte(BucketSendersByLogin)); err != nil {
-			return fmt.Errorf("can't create index for Senders: %v", err)
-		}
-		if _, err := tx.CreateBucketIfNotExists([]byte(BucketMessages)); err != nil {
-			return fmt.Errorf("can't create bucket Messages: %v", err)
-		}
-		if _, err := tx.CreateBucketIfNotExists([]byte(BucketMessageIndex)); err != nil {
-			return fmt.Errorf("can't create bucket MessageIndex: %v", err)
+		for _, bucket := range buckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+				return fmt.Errorf("can't create bucket %s: %v", bucket, err)
+			}
 		}
 		return nil
 	})
 	if err != nil {
 		log.Fatalf("Can't create buckets: %v", err)
 	}
+	if err := db.Update(migrateMessageIndex); err != nil {
+		log.Fatalf("Can't migrate message index: %v", err)
+	}
 }
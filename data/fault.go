@@ -0,0 +1,208 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"sort"
+	"time"
+)
+
+// chaos modes break the connection underneath HTTP
+const (
+	ChaosReset    = "reset"
+	ChaosTruncate = "truncate"
+	ChaosSlow     = "slow"
+)
+
+// keyMaintenance is a key of maintenance mode in the admin bucket
+const keyMaintenance = "maintenance"
+
+// FaultProfile makes API misbehave for matching requests
+type FaultProfile struct {
+	FaultUuid uuid.UUID
+	Name      string
+	// Route is a route pattern like /api/v1/message/:messageUuid or a request path, empty for any
+	Route string
+	// Method is an HTTP method, empty for any
+	Method string
+	// Login is a sender's login, empty for any
+	Login string
+	// Latency is added to every matching request, plus a random value up to Jitter
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate is a percentage of requests answered with a random status of ErrorStatuses
+	ErrorRate     float64
+	ErrorStatuses []int
+	// MalformedRate is a percentage of responses cut in the middle of the body
+	MalformedRate float64
+	// Chaos is applied to a ChaosRate percentage of responses: reset sends half of the body and resets
+	// the connection, truncate closes it after half of the body, slow sends DripBytes every DripInterval
+	Chaos        string
+	ChaosRate    float64
+	DripBytes    int
+	DripInterval time.Duration
+	Create       time.Time
+}
+
+// Maintenance mode makes every API request fail with 503
+type Maintenance struct {
+	Enabled bool
+	// RetryAfter in seconds
+	RetryAfter int
+	Message    string
+}
+
+func (s *FaultProfile) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *FaultProfile) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *FaultProfile) Validate() error {
+	if s.ErrorRate < 0 || s.ErrorRate > 100 {
+		return fmt.Errorf("error rate must be from 0 to 100")
+	}
+	if s.MalformedRate < 0 || s.MalformedRate > 100 {
+		return fmt.Errorf("malformed rate must be from 0 to 100")
+	}
+	if s.Latency < 0 || s.Jitter < 0 {
+		return fmt.Errorf("latency and jitter can't be negative")
+	}
+	switch s.Chaos {
+	case "", ChaosReset, ChaosTruncate, ChaosSlow:
+	default:
+		return fmt.Errorf("unknown chaos mode %s", s.Chaos)
+	}
+	if s.ChaosRate < 0 || s.ChaosRate > 100 {
+		return fmt.Errorf("chaos rate must be from 0 to 100")
+	}
+	if s.DripBytes < 0 || s.DripInterval < 0 {
+		return fmt.Errorf("drip bytes and interval can't be negative")
+	}
+	for _, status := range s.ErrorStatuses {
+		if status < 400 || status > 599 {
+			return fmt.Errorf("error status %d is not an error", status)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the request is affected by the profile
+func (s *FaultProfile) Matches(route, path, method, login string) bool {
+	if len(s.Route) > 0 && s.Route != route && s.Route != path {
+		return false
+	}
+	if len(s.Method) > 0 && s.Method != method {
+		return false
+	}
+	if len(s.Login) > 0 && s.Login != login {
+		return false
+	}
+	return true
+}
+
+func (s *FaultProfile) Save(db *bbolt.DB) error {
+	s.FaultUuid = uuid.New()
+	s.Create = time.Now()
+	return db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket([]byte(BucketFaults)).Put(s.FaultUuid[:], s.Bytes()); err != nil {
+			return fmt.Errorf("can't save fault profile: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *FaultProfile) LoadById(db *bbolt.DB, id uuid.UUID) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		bindata := tx.Bucket([]byte(BucketFaults)).Get(id[:])
+		if bindata == nil {
+			return fmt.Errorf("fault profile not found: %s", id.String())
+		}
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse fault profile data: %v %s", err, string(bindata))
+		}
+		return nil
+	})
+}
+
+func (s *FaultProfile) Delete(db *bbolt.DB, id uuid.UUID) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketFaults := tx.Bucket([]byte(BucketFaults))
+		if bucketFaults.Get(id[:]) == nil {
+			return fmt.Errorf("fault profile not found")
+		}
+		if err := bucketFaults.Delete(id[:]); err != nil {
+			return fmt.Errorf("can't delete fault profile: %v", err)
+		}
+		return nil
+	})
+}
+
+// List returns all profiles from the oldest to the newest
+func (s *FaultProfile) List(db *bbolt.DB) ([]*FaultProfile, error) {
+	ret := make([]*FaultProfile, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(BucketFaults)).ForEach(func(k, v []byte) error {
+			profile := &FaultProfile{}
+			if err := profile.FromBytes(v); err != nil {
+				return fmt.Errorf("can't parse fault profile: %v, %s", err, string(v))
+			}
+			ret = append(ret, profile)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Create.Before(ret[j].Create)
+	})
+	return ret, nil
+}
+
+// Match returns the oldest profile matching the request or nil
+func (s *FaultProfile) Match(db *bbolt.DB, route, path, method, login string) (*FaultProfile, error) {
+	profiles, err := s.List(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		if profile.Matches(route, path, method, login) {
+			return profile, nil
+		}
+	}
+	return nil, nil
+}
+
+// Load reads maintenance mode, it's disabled if never saved
+func (s *Maintenance) Load(db *bbolt.DB) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		bindata := tx.Bucket([]byte(BucketAdmin)).Get([]byte(keyMaintenance))
+		if bindata == nil {
+			*s = Maintenance{}
+			return nil
+		}
+		if err := json.Unmarshal(bindata, s); err != nil {
+			return fmt.Errorf("can't parse maintenance data: %v %s", err, string(bindata))
+		}
+		return nil
+	})
+}
+
+func (s *Maintenance) Save(db *bbolt.DB) error {
+	bindata, _ := json.Marshal(s)
+	return db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket([]byte(BucketAdmin)).Put([]byte(keyMaintenance), bindata); err != nil {
+			return fmt.Errorf("can't save maintenance: %v", err)
+		}
+		return nil
+	})
+}
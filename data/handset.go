@@ -0,0 +1,257 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go.etcd.io/bbolt"
+	"strings"
+	"time"
+)
+
+// states of handsets
+const (
+	HandsetOnline      = "ONLINE"
+	HandsetOffline     = "OFFLINE"
+	HandsetSwitchedOff = "SWITCHED_OFF"
+	HandsetMemoryFull  = "MEMORY_FULL"
+	HandsetRoaming     = "ROAMING"
+)
+
+// Handset is a virtual phone, messages to it aren't delivered while it's unavailable:
+// SMSC stores them and forwards when the handset is back or they expire
+type Handset struct {
+	// PhoneNumber is in E.164 format
+	PhoneNumber string
+	State       string
+	Create      time.Time
+	Updated     time.Time
+}
+
+func (s *Handset) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *Handset) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate checks the state upper-casing it, ONLINE is the default
+func (s *Handset) Validate() error {
+	if !strings.HasPrefix(s.PhoneNumber, "+") {
+		return fmt.Errorf("phone number %s isn't in E.164 format", s.PhoneNumber)
+	}
+	s.State = strings.ToUpper(s.State)
+	switch s.State {
+	case "":
+		s.State = HandsetOnline
+	case HandsetOnline, HandsetOffline, HandsetSwitchedOff, HandsetMemoryFull, HandsetRoaming:
+	default:
+		return fmt.Errorf("unknown state %s", s.State)
+	}
+	return nil
+}
+
+// Available reports whether messages can be delivered to the handset
+func (s *Handset) Available() bool {
+	return s.State == HandsetOnline || s.State == HandsetRoaming
+}
+
+// handsetAvailable reports whether messages can be delivered to the number, numbers without a handset always can
+func handsetAvailable(tx *bbolt.Tx, phoneNumber string) bool {
+	if len(phoneNumber) == 0 {
+		return true
+	}
+	bindata := tx.Bucket([]byte(BucketHandsets)).Get([]byte(phoneNumber))
+	if bindata == nil {
+		return true
+	}
+	handset := &Handset{}
+	if err := handset.FromBytes(bindata); err != nil {
+		return true
+	}
+	return handset.Available()
+}
+
+// heldKey is a key in the held messages bucket, grouped by the number
+func heldKey(phoneNumber string, msg *Message) []byte {
+	key := make([]byte, 0, len(phoneNumber)+1+len(msg.MessageUuid))
+	key = append(key, phoneNumber...)
+	key = append(key, 0)
+	return append(key, msg.MessageUuid[:]...)
+}
+
+func heldPrefix(phoneNumber string) []byte {
+	return append([]byte(phoneNumber), 0)
+}
+
+// Save creates the handset or changes its state, messages held for it are released when it becomes available
+func (s *Handset) Save(db *bbolt.DB) error {
+	s.Updated = time.Now()
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketHandsets := tx.Bucket([]byte(BucketHandsets))
+		s.Create = s.Updated
+		if bindata := bucketHandsets.Get([]byte(s.PhoneNumber)); bindata != nil {
+			existing := &Handset{}
+			if err := existing.FromBytes(bindata); err == nil {
+				s.Create = existing.Create
+			}
+		}
+		if err := bucketHandsets.Put([]byte(s.PhoneNumber), s.Bytes()); err != nil {
+			return fmt.Errorf("can't save handset: %v", err)
+		}
+		if s.Available() {
+			return releaseHeld(tx, s.PhoneNumber, s.Updated)
+		}
+		return nil
+	})
+}
+
+// Load loads the handset of the E.164 number, found is false if there is none
+func (s *Handset) Load(db *bbolt.DB, phoneNumber string) (found bool, err error) {
+	err = db.View(func(tx *bbolt.Tx) error {
+		bindata := tx.Bucket([]byte(BucketHandsets)).Get([]byte(phoneNumber))
+		if bindata == nil {
+			return nil
+		}
+		found = true
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse handset data: %v %s", err, string(bindata))
+		}
+		return nil
+	})
+	return found, err
+}
+
+// Delete removes the handset releasing messages held for it
+func (s *Handset) Delete(db *bbolt.DB, phoneNumber string) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketHandsets := tx.Bucket([]byte(BucketHandsets))
+		if bucketHandsets.Get([]byte(phoneNumber)) == nil {
+			return fmt.Errorf("handset not found")
+		}
+		if err := bucketHandsets.Delete([]byte(phoneNumber)); err != nil {
+			return fmt.Errorf("can't delete handset: %v", err)
+		}
+		return releaseHeld(tx, phoneNumber, time.Now())
+	})
+}
+
+// List returns handsets sorted by number
+func (s *Handset) List(db *bbolt.DB, limit, offset int) ([]*Handset, error) {
+	ret := make([]*Handset, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		iterator := tx.Bucket([]byte(BucketHandsets)).Cursor()
+		if limit == 0 {
+			limit = 10
+		}
+		limit += offset
+		i := 0
+		for k, v := iterator.First(); k != nil && i < limit; k, v = iterator.Next() {
+			i += 1
+			if i <= offset {
+				continue
+			}
+			handset := &Handset{}
+			if err := handset.FromBytes(v); err != nil {
+				return fmt.Errorf("can't parse handset: %v, %s", err, string(v))
+			}
+			ret = append(ret, handset)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Held returns the number of messages stored for the handset
+func (s *Handset) Held(db *bbolt.DB, phoneNumber string) (int, error) {
+	ret := 0
+	err := db.View(func(tx *bbolt.Tx) error {
+		prefix := heldPrefix(phoneNumber)
+		iterator := tx.Bucket([]byte(BucketHeldMessages)).Cursor()
+		for k, _ := iterator.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = iterator.Next() {
+			ret += 1
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// deliveryDue reports whether the next transition delivers the message
+func (s *Message) deliveryDue() bool {
+	return len(s.Plan) > 0 && s.Plan[0].Status == StatusDelivered && (s.Expires.IsZero() || s.Expires.After(s.NextUpdate))
+}
+
+// hold stops the message before delivery until its handset is available, it still expires
+func (s *Message) hold(tx *bbolt.Tx) error {
+	s.Held = true
+	s.NextUpdate = s.Expires
+	if err := tx.Bucket([]byte(BucketHeldMessages)).Put(heldKey(s.Destination, s), s.MessageUuid[:]); err != nil {
+		return fmt.Errorf("can't save held message: %v", err)
+	}
+	return nil
+}
+
+// unhold forgets the message was held
+func (s *Message) unhold(tx *bbolt.Tx) error {
+	s.Held = false
+	if err := tx.Bucket([]byte(BucketHeldMessages)).Delete(heldKey(s.Destination, s)); err != nil {
+		return fmt.Errorf("can't delete held message: %v", err)
+	}
+	return nil
+}
+
+// releaseHeld schedules delivery of messages held for the number, the delivery takes its planned delay from now
+func releaseHeld(tx *bbolt.Tx, phoneNumber string, now time.Time) error {
+	bucketMessages := tx.Bucket([]byte(BucketMessages))
+	bucketPending := tx.Bucket([]byte(BucketMessagePending))
+	bucketHeld := tx.Bucket([]byte(BucketHeldMessages))
+	prefix := heldPrefix(phoneNumber)
+	held := make([][]byte, 0)
+	iterator := bucketHeld.Cursor()
+	for k, _ := iterator.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = iterator.Next() {
+		held = append(held, append([]byte{}, k...))
+	}
+	for _, k := range held {
+		id := bucketHeld.Get(k)
+		if err := bucketHeld.Delete(k); err != nil {
+			return fmt.Errorf("can't delete held message: %v", err)
+		}
+		bindata := bucketMessages.Get(id)
+		if bindata == nil {
+			// message was deleted
+			continue
+		}
+		msg := &Message{}
+		if err := msg.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse message: %v, %s", err, string(bindata))
+		}
+		if !msg.NextUpdate.IsZero() {
+			if err := bucketPending.Delete(msg.PendingKey()); err != nil {
+				return fmt.Errorf("can't delete pending message: %v", err)
+			}
+		}
+		msg.Held = false
+		msg.NextUpdate = now
+		if len(msg.Plan) > 0 {
+			msg.NextUpdate = now.Add(msg.Plan[0].Delay)
+		}
+		if !msg.Expires.IsZero() && msg.Expires.Before(msg.NextUpdate) {
+			msg.NextUpdate = msg.Expires
+		}
+		if err := bucketMessages.Put(msg.MessageUuid[:], msg.Bytes()); err != nil {
+			return fmt.Errorf("can't save message: %v", err)
+		}
+		if err := bucketPending.Put(msg.PendingKey(), msg.MessageUuid[:]); err != nil {
+			return fmt.Errorf("can't save pending message: %v", err)
+		}
+	}
+	return nil
+}
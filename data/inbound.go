@@ -0,0 +1,153 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"time"
+)
+
+// Inbound is a mobile originated message: a reply from a handset
+type Inbound struct {
+	InboundUuid uuid.UUID
+	SenderUuid  uuid.UUID
+	From        string
+	To          string
+	Text        string
+	Create      time.Time
+}
+
+func (s *Inbound) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *Inbound) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Inbound) Index() []byte {
+	return phoneIndex(s.From, s.Create, s.InboundUuid)
+}
+
+func (s *Inbound) GetInboundBuckets(tx *bbolt.Tx) (*bbolt.Bucket, *bbolt.Bucket, error) {
+	bucketInbound := tx.Bucket([]byte(BucketInbound))
+	if bucketInbound == nil {
+		return nil, nil, fmt.Errorf("can't get bucket for inbound messages")
+	}
+	bucketInboundIndex := tx.Bucket([]byte(BucketInboundIndex))
+	if bucketInboundIndex == nil {
+		return nil, nil, fmt.Errorf("can't get bucket for inbound message index")
+	}
+	return bucketInbound, bucketInboundIndex, nil
+}
+
+func (s *Inbound) Save(db *bbolt.DB) error {
+	s.InboundUuid = uuid.New()
+	s.Create = time.Now()
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketInbound, bucketInboundIndex, err := s.GetInboundBuckets(tx)
+		if err != nil {
+			return err
+		}
+		if err := bucketInbound.Put(s.InboundUuid[:], s.Bytes()); err != nil {
+			return fmt.Errorf("can't save inbound message: %v", err)
+		}
+		if err := bucketInboundIndex.Put(s.Index(), s.InboundUuid[:]); err != nil {
+			return fmt.Errorf("can't save inbound message index: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Inbound) LoadById(db *bbolt.DB, id uuid.UUID) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		bucketInbound, _, err := s.GetInboundBuckets(tx)
+		if err != nil {
+			return err
+		}
+		bindata := bucketInbound.Get(id[:])
+		if bindata == nil {
+			return fmt.Errorf("inbound message not found: %s", id.String())
+		}
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse inbound message data: %v %s", err, string(bindata))
+		}
+		return nil
+	})
+}
+
+func (s *Inbound) Delete(db *bbolt.DB, id uuid.UUID) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketInbound, bucketInboundIndex, err := s.GetInboundBuckets(tx)
+		if err != nil {
+			return err
+		}
+		existing := bucketInbound.Get(id[:])
+		if existing == nil {
+			return fmt.Errorf("inbound message not found")
+		}
+		if err := s.FromBytes(existing); err != nil {
+			return fmt.Errorf("can't parse existing inbound message data: %v %s", err, string(existing))
+		}
+		if err := bucketInbound.Delete(id[:]); err != nil {
+			return fmt.Errorf("can't delete inbound message: %v", err)
+		}
+		if err := bucketInboundIndex.Delete(s.Index()); err != nil {
+			return fmt.Errorf("can't delete inbound message index: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Inbound) getInboundFromBucket(bucketInbound *bbolt.Bucket, id []byte, index []byte) (*Inbound, error) {
+	bindata := bucketInbound.Get(id)
+	if bindata == nil {
+		return nil, fmt.Errorf("can't find inbound message by id %v from index %v", id, index)
+	}
+	in := &Inbound{}
+	if err := in.FromBytes(bindata); err != nil {
+		return nil, fmt.Errorf("can't parse inbound message: %v, %s", err, string(bindata))
+	}
+	return in, nil
+}
+
+// List returns inbound messages sorted by phone number and time, only from the phone if it isn't empty
+func (s *Inbound) List(db *bbolt.DB, phone string, limit, offset int) ([]*Inbound, error) {
+	ret := make([]*Inbound, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		bucketInbound, bucketInboundIndex, err := s.GetInboundBuckets(tx)
+		if err != nil {
+			return err
+		}
+		iterator := bucketInboundIndex.Cursor()
+		if limit == 0 {
+			limit = 10
+		}
+		limit += offset
+		prefix := []byte(phone)
+		i := 0
+		for k, v := iterator.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix) && i < limit; k, v = iterator.Next() {
+			i += 1
+			if i <= offset {
+				continue
+			}
+			in, err := s.getInboundFromBucket(bucketInbound, v, k)
+			if err != nil {
+				return err
+			}
+			ret = append(ret, in)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	} else {
+		return ret, nil
+	}
+}
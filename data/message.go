@@ -2,25 +2,158 @@ package data
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"go.etcd.io/bbolt"
+	"strings"
 	"time"
 )
 
+const (
+	StatusQueued      = "QUEUED"
+	StatusAccepted    = "ACCEPTED"
+	StatusSent        = "SENT"
+	StatusDelivered   = "DELIVERED"
+	StatusUndelivered = "UNDELIVERED"
+	StatusExpired     = "EXPIRED"
+	StatusRejected    = "REJECTED"
+	// StatusScheduled waits for SendAt, StatusCanceled was canceled before it went out
+	StatusScheduled = "SCHEDULED"
+	StatusCanceled  = "CANCELED"
+)
+
+// knownStatuses can be planned in lifecycles and rules, the value tells if the status is final
+var knownStatuses = map[string]bool{
+	StatusQueued:      false,
+	StatusAccepted:    false,
+	StatusSent:        false,
+	StatusDelivered:   true,
+	StatusUndelivered: true,
+	StatusExpired:     true,
+	StatusRejected:    true,
+}
+
+// requestedStatuses are set on requests of the API only and can't be planned
+var requestedStatuses = map[string]bool{
+	StatusScheduled: false,
+	StatusCanceled:  true,
+}
+
+// IsFinalStatus reports whether a message can't leave the status anymore
+func IsFinalStatus(status string) bool {
+	return knownStatuses[status] || requestedStatuses[status]
+}
+
+// IsPlannedStatus reports whether the status can be used in lifecycles and rules
+func IsPlannedStatus(status string) bool {
+	_, ok := knownStatuses[status]
+	return ok
+}
+
+// message types
+const (
+	MessageTypeText  = "TEXT"
+	MessageTypeFlash = "FLASH"
+)
+
+// MessageTypes are the message types accepted in strict validation mode
+var MessageTypes = []string{MessageTypeText, MessageTypeFlash}
+
+// LifecycleStep is a planned transition: Delay after the previous transition message moves to Status
+type LifecycleStep struct {
+	Status string
+	Delay  time.Duration
+	// ErrorCode and ErrorMessage explain a failed final status
+	ErrorCode    int    `json:",omitempty"`
+	ErrorMessage string `json:",omitempty"`
+}
+
+// ParseLifecycle parses lifecycle definition like "ACCEPTED:1s,SENT:1s,DELIVERED:2s"
+func ParseLifecycle(src string) ([]LifecycleStep, error) {
+	steps := make([]LifecycleStep, 0)
+	for _, item := range strings.Split(src, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 2)
+		step := LifecycleStep{Status: strings.ToUpper(strings.TrimSpace(parts[0]))}
+		if !IsPlannedStatus(step.Status) {
+			return nil, fmt.Errorf("unknown status %s", step.Status)
+		}
+		if len(parts) > 1 {
+			delay, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, fmt.Errorf("can't parse delay for %s: %v", step.Status, err)
+			}
+			step.Delay = delay
+		}
+		if len(steps) > 0 && IsFinalStatus(steps[len(steps)-1].Status) {
+			return nil, fmt.Errorf("status %s follows final status %s", step.Status, steps[len(steps)-1].Status)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
 type Message struct {
-	MessageUuid       uuid.UUID
-	Sender            *Sender `json:"-"`
-	SenderUuid        uuid.UUID
-	SenderName        string
-	MessageType       string
-	MessageText       string
+	MessageUuid uuid.UUID
+	Sender      *Sender `json:"-"`
+	SenderUuid  uuid.UUID
+	SenderName  string
+	MessageType string
+	MessageText string
+	// ExpirationTimeout in seconds, 0 means the message never expires
 	ExpirationTimeout int
-	PhoneNumber       string
-	Status            string
-	Create            time.Time
-	Sent              time.Time
+	// SendAt holds the message as SCHEDULED until the time, it's queued right away if it's zero or in the past
+	SendAt      time.Time
+	PhoneNumber string
+	// CallbackUrl overrides sender's default URL for delivery reports
+	CallbackUrl string
+	// Dialect is an interface the message came from, empty for REST API
+	Dialect string
+	// RegisteredDelivery is SMPP registered_delivery flag of the message
+	RegisteredDelivery int
+	// DlrMask is Kannel dlr-mask of the message: statuses to report to CallbackUrl
+	DlrMask int
+	// Transliterate overrides the sender's option to replace non GSM-7 characters,
+	// OriginalText and OriginalSegments are kept when the text was changed
+	Transliterate    *bool `json:",omitempty"`
+	OriginalText     string
+	OriginalSegments int
+	// Encoding is GSM7 or UCS2, Parts are the text split into Segments as they are sent
+	Encoding string
+	Segments int
+	Parts    []string
+	// Country, Mcc, Mnc and Carrier are resolved from the phone number, empty if unknown
+	Country string `json:",omitempty"`
+	Mcc     string `json:",omitempty"`
+	Mnc     string `json:",omitempty"`
+	Carrier string `json:",omitempty"`
+	// Destination is the number in E.164 format, empty if it can't be normalized
+	Destination string `json:",omitempty"`
+	// Held is set while the message waits for its handset to become available
+	Held bool `json:",omitempty"`
+	// BatchUuid is the batch the message was submitted in, nil for single messages
+	BatchUuid uuid.UUID
+	// CampaignUuid is the campaign which sent the message, nil for other messages
+	CampaignUuid uuid.UUID
+	// Price is debited from the sender's balance, refunded if the message fails
+	Price float64 `json:",omitempty"`
+	// Rule is a name of the rule which planned the outcome
+	Rule         string
+	ErrorCode    int
+	ErrorMessage string
+	Status       string
+	Create       time.Time
+	Sent         time.Time
+	Updated      time.Time
+	Done         time.Time
+	Expires      time.Time
+	NextUpdate   time.Time
+	Plan         []LifecycleStep
 }
 
 func (s *Message) Bytes() []byte {
@@ -36,37 +169,218 @@ func (s *Message) FromBytes(bindata []byte) error {
 }
 
 func (s *Message) Index() []byte {
-	suf := []byte(s.Create.UTC().Format("2006-01-02 15:04:05.999"))
+	return phoneIndex(s.PhoneNumber, s.Create, s.MessageUuid)
+}
+
+// phoneIndex makes a key sorted by phone number and then by time from newest to oldest
+func phoneIndex(phone string, created time.Time, id uuid.UUID) []byte {
+	suf := []byte(created.UTC().Format("2006-01-02 15:04:05.999"))
 	// we need for reversed sort
 	for i := 0; i < len(suf); i++ {
 		suf[i] = 255 - suf[i]
 	}
-	return append([]byte(s.PhoneNumber), suf...)
+	// id makes index unique for records created at the same millisecond
+	return append(append([]byte(phone), suf...), id[:]...)
+}
+
+// migrateMessageIndex rewrites index keys written without the message id and drops keys of deleted messages
+func migrateMessageIndex(tx *bbolt.Tx) error {
+	bucketMessages, bucketMessageIndex, err := (&Message{}).GetMessageBuckets(tx)
+	if err != nil {
+		return err
+	}
+	stale := make([][]byte, 0)
+	fixed := make([]*Message, 0)
+	err = bucketMessageIndex.ForEach(func(k, v []byte) error {
+		bindata := bucketMessages.Get(v)
+		if bindata == nil {
+			stale = append(stale, append([]byte{}, k...))
+			return nil
+		}
+		msg := &Message{}
+		if err := msg.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse message: %v, %s", err, string(bindata))
+		}
+		if !bytes.Equal(k, msg.Index()) {
+			stale = append(stale, append([]byte{}, k...))
+			fixed = append(fixed, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range stale {
+		if err := bucketMessageIndex.Delete(k); err != nil {
+			return fmt.Errorf("can't delete message index: %v", err)
+		}
+	}
+	for _, msg := range fixed {
+		if err := bucketMessageIndex.Put(msg.Index(), msg.MessageUuid[:]); err != nil {
+			return fmt.Errorf("can't save message index: %v", err)
+		}
+	}
+	return nil
+}
+
+// PendingKey is a key in the pending bucket, sorted by the time of the next transition
+func (s *Message) PendingKey() []byte {
+	key := make([]byte, 8, 8+len(s.MessageUuid))
+	binary.BigEndian.PutUint64(key, uint64(s.NextUpdate.UnixNano()))
+	return append(key, s.MessageUuid[:]...)
+}
+
+// Advance makes the transition planned at NextUpdate: the next status from the plan or EXPIRED
+func (s *Message) Advance() {
+	at := s.NextUpdate
+	if !s.Expires.IsZero() && !s.Expires.After(at) {
+		s.SetStatus(StatusExpired, s.Expires)
+		return
+	}
+	if len(s.Plan) == 0 {
+		return
+	}
+	step := s.Plan[0]
+	s.Plan = s.Plan[1:]
+	if step.ErrorCode != 0 || len(step.ErrorMessage) > 0 {
+		s.ErrorCode = step.ErrorCode
+		s.ErrorMessage = step.ErrorMessage
+	}
+	s.SetStatus(step.Status, at)
+}
+
+// SetStatus updates status with its timestamps and schedules the next transition
+func (s *Message) SetStatus(status string, now time.Time) {
+	s.Status = status
+	s.Updated = now
+	if status == StatusSent {
+		s.Sent = now
+	}
+	if IsFinalStatus(status) {
+		s.Done = now
+		s.Plan = nil
+	}
+	s.scheduleNext()
+}
+
+func (s *Message) scheduleNext() {
+	s.NextUpdate = time.Time{}
+	if IsFinalStatus(s.Status) {
+		return
+	}
+	if len(s.Plan) > 0 {
+		s.NextUpdate = s.Updated.Add(s.Plan[0].Delay)
+	}
+	if !s.Expires.IsZero() && (s.NextUpdate.IsZero() || s.Expires.Before(s.NextUpdate)) {
+		s.NextUpdate = s.Expires
+	}
 }
 
 func (s *Message) Save(db *bbolt.DB) error {
+	s.prepare()
+	return db.Update(s.insert)
+}
+
+// prepare sets id, timestamps and the first transition of the new message
+func (s *Message) prepare() {
 	s.MessageUuid = uuid.New()
 	s.Create = time.Now()
-	s.Sent = time.Now()
-	s.Status = "SENT"
+	s.Updated = s.Create
+	s.Status = StatusQueued
 	s.SenderUuid = s.Sender.SenderUuid
-	return db.Update(func(tx *bbolt.Tx) error {
-		bucketMessages, bucketMessageIndex, err := s.GetMessageBuckets(tx)
-		if err != nil {
-			return err
+	start := s.Create
+	if s.SendAt.After(s.Create) {
+		// the message is queued at SendAt and follows its plan from there
+		s.Status = StatusScheduled
+		s.Plan = append([]LifecycleStep{{Status: StatusQueued, Delay: s.SendAt.Sub(s.Create)}}, s.Plan...)
+		start = s.SendAt
+	}
+	if s.ExpirationTimeout > 0 {
+		s.Expires = start.Add(time.Duration(s.ExpirationTimeout) * time.Second)
+	}
+	s.scheduleNext()
+}
+
+// insert puts the new message with its indexes
+func (s *Message) insert(tx *bbolt.Tx) error {
+	bucketMessages, bucketMessageIndex, err := s.GetMessageBuckets(tx)
+	if err != nil {
+		return err
+	}
+	if err := bucketMessages.Put(s.MessageUuid[:], s.Bytes()); err != nil {
+		return fmt.Errorf("can't save message: %v", err)
+	}
+	if err := bucketMessageIndex.Put(s.Index(), s.MessageUuid[:]); err != nil {
+		return fmt.Errorf("can't save message index: %v", err)
+	}
+	if !s.NextUpdate.IsZero() {
+		if err := tx.Bucket([]byte(BucketMessagePending)).Put(s.PendingKey(), s.MessageUuid[:]); err != nil {
+			return fmt.Errorf("can't save pending message: %v", err)
 		}
-		if err := bucketMessages.Put(s.MessageUuid[:], s.Bytes()); err != nil {
-			return fmt.Errorf("can't save message: %v", err)
+	}
+	return nil
+}
+
+// AdvanceDue moves all messages with due transitions and returns snapshots of the messages after each transition
+func (s *Message) AdvanceDue(db *bbolt.DB, now time.Time) ([]*Message, error) {
+	ret := make([]*Message, 0)
+	err := db.Update(func(tx *bbolt.Tx) error {
+		bucketMessages := tx.Bucket([]byte(BucketMessages))
+		bucketPending := tx.Bucket([]byte(BucketMessagePending))
+		if bucketMessages == nil || bucketPending == nil {
+			return fmt.Errorf("can't get buckets for pending messages")
 		}
-		idx := s.Index()
-		if bindata := bucketMessageIndex.Get(idx); bindata != nil {
-			return fmt.Errorf("message index already exists: possible throttling")
+		due := make([][]byte, 0)
+		iterator := bucketPending.Cursor()
+		for k, _ := iterator.First(); k != nil && int64(binary.BigEndian.Uint64(k)) <= now.UnixNano(); k, _ = iterator.Next() {
+			due = append(due, append([]byte{}, k...))
 		}
-		if err := bucketMessageIndex.Put(idx, s.MessageUuid[:]); err != nil {
-			return fmt.Errorf("can't save message index: %v", err)
+		for _, k := range due {
+			id := bucketPending.Get(k)
+			if err := bucketPending.Delete(k); err != nil {
+				return fmt.Errorf("can't delete pending message: %v", err)
+			}
+			bindata := bucketMessages.Get(id)
+			if bindata == nil {
+				// message was deleted
+				continue
+			}
+			msg := &Message{}
+			if err := msg.FromBytes(bindata); err != nil {
+				return fmt.Errorf("can't parse message: %v, %s", err, string(bindata))
+			}
+			for !msg.NextUpdate.IsZero() && !msg.NextUpdate.After(now) {
+				if msg.Held {
+					// only the expiration moves a held message
+					if err := msg.unhold(tx); err != nil {
+						return err
+					}
+				} else if msg.deliveryDue() && !handsetAvailable(tx, msg.Destination) {
+					if err := msg.hold(tx); err != nil {
+						return err
+					}
+					continue
+				}
+				msg.Advance()
+				snapshot := *msg
+				ret = append(ret, &snapshot)
+			}
+			if err := bucketMessages.Put(msg.MessageUuid[:], msg.Bytes()); err != nil {
+				return fmt.Errorf("can't save message: %v", err)
+			}
+			if !msg.NextUpdate.IsZero() {
+				if err := bucketPending.Put(msg.PendingKey(), msg.MessageUuid[:]); err != nil {
+					return fmt.Errorf("can't save pending message: %v", err)
+				}
+			}
 		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	} else {
+		return ret, nil
+	}
 }
 
 func (s *Message) LoadById(db *bbolt.DB, id uuid.UUID) error {
@@ -105,6 +419,46 @@ func (s *Message) Delete(db *bbolt.DB, id uuid.UUID) error {
 		if err := bucketMessageIndex.Delete(s.Index()); err != nil {
 			return fmt.Errorf("can't delete message index: %v", err)
 		}
+		if !s.NextUpdate.IsZero() {
+			if err := tx.Bucket([]byte(BucketMessagePending)).Delete(s.PendingKey()); err != nil {
+				return fmt.Errorf("can't delete pending message: %v", err)
+			}
+		}
+		if s.Held {
+			return s.unhold(tx)
+		}
+		return nil
+	})
+}
+
+// Cancel moves the message which hasn't gone out yet to CANCELED
+func (s *Message) Cancel(db *bbolt.DB, id uuid.UUID, now time.Time) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketMessages := tx.Bucket([]byte(BucketMessages))
+		bindata := bucketMessages.Get(id[:])
+		if bindata == nil {
+			return fmt.Errorf("message not found")
+		}
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse message data: %v %s", err, string(bindata))
+		}
+		if s.Status != StatusScheduled && s.Status != StatusQueued {
+			return fmt.Errorf("message in status %s can't be canceled", s.Status)
+		}
+		if !s.NextUpdate.IsZero() {
+			if err := tx.Bucket([]byte(BucketMessagePending)).Delete(s.PendingKey()); err != nil {
+				return fmt.Errorf("can't delete pending message: %v", err)
+			}
+		}
+		if s.Held {
+			if err := s.unhold(tx); err != nil {
+				return err
+			}
+		}
+		s.SetStatus(StatusCanceled, now)
+		if err := bucketMessages.Put(s.MessageUuid[:], s.Bytes()); err != nil {
+			return fmt.Errorf("can't save message: %v", err)
+		}
 		return nil
 	})
 }
@@ -190,3 +544,41 @@ func (s *Message) List(db *bbolt.DB, limit, offset int) ([]*Message, error) {
 		return ret, nil
 	}
 }
+
+// ListFiltered returns messages accepted by the filter, only for phone numbers starting with phone if it isn't empty
+func (s *Message) ListFiltered(db *bbolt.DB, phone string, filter func(msg *Message) bool, limit, offset int) ([]*Message, error) {
+	ret := make([]*Message, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		bucketMessages, bucketMessageIndex, err := s.GetMessageBuckets(tx)
+		if err != nil {
+			return err
+		}
+		iterator := bucketMessageIndex.Cursor()
+		if limit == 0 {
+			limit = 10
+		}
+		limit += offset
+		prefix := []byte(phone)
+		i := 0
+		for k, v := iterator.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix) && i < limit; k, v = iterator.Next() {
+			msg, err := s.GetMessageFromBucket(bucketMessages, v, k)
+			if err != nil {
+				return err
+			}
+			if !filter(msg) {
+				continue
+			}
+			i += 1
+			if i <= offset {
+				continue
+			}
+			ret = append(ret, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	} else {
+		return ret, nil
+	}
+}
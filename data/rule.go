@@ -0,0 +1,227 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rule sets the outcome of messages matching all its non-empty conditions
+type Rule struct {
+	RuleUuid uuid.UUID
+	Name     string
+	// Priority orders rules, lower is checked first
+	Priority int
+	// conditions
+	PhonePrefix  string
+	PhoneSuffix  string
+	PhoneRegex   string
+	TextContains string
+	TextRegex    string
+	SenderName   string
+	MessageType  string
+	Login        string
+	// outcome: the final status, its error and delay, zero delay keeps the lifecycle one
+	Status       string
+	ErrorCode    int
+	ErrorMessage string
+	Delay        time.Duration
+	Create       time.Time
+}
+
+// MagicNumbers is a built-in catalog of rules by phone number suffix, checked after user rules
+var MagicNumbers = []*Rule{
+	{Name: "magic: unknown subscriber", PhoneSuffix: "0001", Status: StatusUndelivered, ErrorCode: 1, ErrorMessage: "unknown subscriber"},
+	{Name: "magic: absent subscriber", PhoneSuffix: "0002", Status: StatusUndelivered, ErrorCode: 27, ErrorMessage: "absent subscriber"},
+	{Name: "magic: call barred", PhoneSuffix: "0003", Status: StatusUndelivered, ErrorCode: 13, ErrorMessage: "call barred"},
+	{Name: "magic: teleservice not provisioned", PhoneSuffix: "0004", Status: StatusUndelivered, ErrorCode: 11, ErrorMessage: "teleservice not provisioned"},
+	{Name: "magic: system failure", PhoneSuffix: "0005", Status: StatusUndelivered, ErrorCode: 34, ErrorMessage: "system failure"},
+	{Name: "magic: rejected by SMSC", PhoneSuffix: "0006", Status: StatusRejected, ErrorCode: 88, ErrorMessage: "message rejected"},
+	{Name: "magic: never delivered", PhoneSuffix: "0007", Status: StatusExpired, ErrorMessage: "validity period expired", Delay: 30 * time.Second},
+	{Name: "magic: slow delivery", PhoneSuffix: "0008", Status: StatusDelivered, Delay: 30 * time.Second},
+}
+
+func (s *Rule) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *Rule) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate checks the outcome and regular expressions
+func (s *Rule) Validate() error {
+	if !IsPlannedStatus(s.Status) {
+		return fmt.Errorf("status %s can't be an outcome", s.Status)
+	}
+	if !IsFinalStatus(s.Status) {
+		return fmt.Errorf("status %s is not final", s.Status)
+	}
+	if s.Delay < 0 {
+		return fmt.Errorf("delay can't be negative")
+	}
+	if _, err := regexp.Compile(s.PhoneRegex); err != nil {
+		return fmt.Errorf("bad phone regex: %v", err)
+	}
+	if _, err := regexp.Compile(s.TextRegex); err != nil {
+		return fmt.Errorf("bad text regex: %v", err)
+	}
+	return nil
+}
+
+// Matches reports whether the message meets all conditions of the rule
+func (s *Rule) Matches(msg *Message) bool {
+	if !strings.HasPrefix(msg.PhoneNumber, s.PhonePrefix) || !strings.HasSuffix(msg.PhoneNumber, s.PhoneSuffix) {
+		return false
+	}
+	if !strings.Contains(msg.MessageText, s.TextContains) {
+		return false
+	}
+	if len(s.SenderName) > 0 && s.SenderName != msg.SenderName {
+		return false
+	}
+	if len(s.MessageType) > 0 && s.MessageType != msg.MessageType {
+		return false
+	}
+	if len(s.Login) > 0 && (msg.Sender == nil || s.Login != msg.Sender.Login) {
+		return false
+	}
+	if len(s.PhoneRegex) > 0 {
+		if re, err := regexp.Compile(s.PhoneRegex); err != nil || !re.MatchString(msg.PhoneNumber) {
+			return false
+		}
+	}
+	if len(s.TextRegex) > 0 {
+		if re, err := regexp.Compile(s.TextRegex); err != nil || !re.MatchString(msg.MessageText) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply replaces the final step of the message plan with the rule outcome
+func (s *Rule) Apply(msg *Message) {
+	step := LifecycleStep{Status: s.Status, ErrorCode: s.ErrorCode, ErrorMessage: s.ErrorMessage}
+	if len(msg.Plan) > 0 {
+		step.Delay = msg.Plan[len(msg.Plan)-1].Delay
+		msg.Plan = msg.Plan[:len(msg.Plan)-1]
+	}
+	if s.Delay > 0 {
+		step.Delay = s.Delay
+	}
+	if s.Status == StatusRejected {
+		// SMSC rejects a message before it's accepted
+		msg.Plan = nil
+	}
+	msg.Plan = append(msg.Plan, step)
+	msg.Rule = s.Name
+}
+
+func (s *Rule) Save(db *bbolt.DB) error {
+	s.RuleUuid = uuid.New()
+	s.Create = time.Now()
+	return db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket([]byte(BucketRules)).Put(s.RuleUuid[:], s.Bytes()); err != nil {
+			return fmt.Errorf("can't save rule: %v", err)
+		}
+		return nil
+	})
+}
+
+// Replace overwrites the existing rule keeping its id and creation time
+func (s *Rule) Replace(db *bbolt.DB, id uuid.UUID) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketRules := tx.Bucket([]byte(BucketRules))
+		existing := bucketRules.Get(id[:])
+		if existing == nil {
+			return fmt.Errorf("rule not found")
+		}
+		old := &Rule{}
+		if err := old.FromBytes(existing); err != nil {
+			return fmt.Errorf("can't parse existing rule data: %v %s", err, string(existing))
+		}
+		s.RuleUuid = old.RuleUuid
+		s.Create = old.Create
+		if err := bucketRules.Put(id[:], s.Bytes()); err != nil {
+			return fmt.Errorf("can't save rule: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Rule) LoadById(db *bbolt.DB, id uuid.UUID) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		bindata := tx.Bucket([]byte(BucketRules)).Get(id[:])
+		if bindata == nil {
+			return fmt.Errorf("rule not found: %s", id.String())
+		}
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse rule data: %v %s", err, string(bindata))
+		}
+		return nil
+	})
+}
+
+func (s *Rule) Delete(db *bbolt.DB, id uuid.UUID) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketRules := tx.Bucket([]byte(BucketRules))
+		if bucketRules.Get(id[:]) == nil {
+			return fmt.Errorf("rule not found")
+		}
+		if err := bucketRules.Delete(id[:]); err != nil {
+			return fmt.Errorf("can't delete rule: %v", err)
+		}
+		return nil
+	})
+}
+
+// List returns all rules in the order they are checked
+func (s *Rule) List(db *bbolt.DB) ([]*Rule, error) {
+	ret := make([]*Rule, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(BucketRules)).ForEach(func(k, v []byte) error {
+			rule := &Rule{}
+			if err := rule.FromBytes(v); err != nil {
+				return fmt.Errorf("can't parse rule: %v, %s", err, string(v))
+			}
+			ret = append(ret, rule)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Priority != ret[j].Priority {
+			return ret[i].Priority < ret[j].Priority
+		}
+		return ret[i].Create.Before(ret[j].Create)
+	})
+	return ret, nil
+}
+
+// Match returns the first user rule matching the message, then the first magic number if magic is set, or nil
+func (s *Rule) Match(db *bbolt.DB, msg *Message, magic bool) (*Rule, error) {
+	rules, err := s.List(db)
+	if err != nil {
+		return nil, err
+	}
+	if magic {
+		rules = append(rules, MagicNumbers...)
+	}
+	for _, rule := range rules {
+		if rule.Matches(msg) {
+			return rule, nil
+		}
+	}
+	return nil, nil
+}
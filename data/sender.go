@@ -1,16 +1,57 @@
 package data
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"go.etcd.io/bbolt"
+	"log"
+	"math"
 )
 
 type Sender struct {
 	SenderUuid uuid.UUID `json:"senderUuid"`
 	Login string `json:"login"`
 	Password string `json:"password,omitempty"`
+	// CallbackUrl is a default URL for delivery reports of the sender's messages
+	CallbackUrl string `json:"callbackUrl,omitempty"`
+	// MoCallbackUrl receives inbound (mobile originated) messages
+	MoCallbackUrl string `json:"moCallbackUrl,omitempty"`
+	// Secret signs webhooks sent on behalf of the sender
+	Secret string `json:"secret,omitempty"`
+	// Transliterate replaces non GSM-7 characters in messages which don't override it
+	Transliterate *bool `json:"transliterate,omitempty"`
+	// RateLimit is messages per second with RateBurst bucket size, DailyQuota and MonthlyQuota cap messages
+	// per UTC day and month, zero means unlimited
+	RateLimit    float64 `json:"rateLimit,omitempty"`
+	RateBurst    int     `json:"rateBurst,omitempty"`
+	DailyQuota   int     `json:"dailyQuota,omitempty"`
+	MonthlyQuota int     `json:"monthlyQuota,omitempty"`
+	// Prepaid senders can't send messages costing more than Balance, it's changed only with ledger entries
+	Prepaid *bool   `json:"prepaid,omitempty"`
+	Balance float64 `json:"balance,omitempty"`
+}
+
+// IsPrepaid reports whether messages of the sender need a balance
+func (s *Sender) IsPrepaid() bool {
+	return s.Prepaid != nil && *s.Prepaid
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func newSecret() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("Can't generate secret: %v", err)
+	}
+	return hex.EncodeToString(buf)
 }
 
 func (s *Sender) Bytes() []byte {
@@ -38,6 +79,9 @@ func getSenderBuckets(tx *bbolt.Tx) (bucketSendersLogins *bbolt.Bucket, bucketSe
 
 func (s *Sender) Save(db *bbolt.DB) error {
 	s.SenderUuid = uuid.New()
+	if len(s.Secret) == 0 {
+		s.Secret = newSecret()
+	}
 	err := db.Update(func(tx *bbolt.Tx) error {
 		bucketSendersLogins, bucketSenders, err := getSenderBuckets(tx)
 		if err != nil {
@@ -82,7 +126,8 @@ func (s *Sender) Delete(db *bbolt.DB, id uuid.UUID) error {
 	return err
 }
 
-func (s *Sender) Edit(db *bbolt.DB) error {
+// Edit changes the set fields of the sender; callback urls are changed when not nil, an empty one is removed
+func (s *Sender) Edit(db *bbolt.DB, callbackUrl, moCallbackUrl *string) error {
 	return db.Update(func(tx *bbolt.Tx) error {
 		bucketSendersLogins, bucketSenders, err := getSenderBuckets(tx)
 		if err != nil {
@@ -108,6 +153,34 @@ func (s *Sender) Edit(db *bbolt.DB) error {
 		if len(s.Password) > 0 && existing.Password !=s.Password {
 			existing.Password = s.Password
 		}
+		if callbackUrl != nil {
+			existing.CallbackUrl = *callbackUrl
+		}
+		if moCallbackUrl != nil {
+			existing.MoCallbackUrl = *moCallbackUrl
+		}
+		if len(s.Secret) > 0 {
+			existing.Secret = s.Secret
+		}
+		if s.Transliterate != nil {
+			existing.Transliterate = s.Transliterate
+		}
+		if s.Prepaid != nil {
+			existing.Prepaid = s.Prepaid
+		}
+		// limits are changed when not zero, negative ones are removed
+		if s.RateLimit != 0 {
+			existing.RateLimit = math.Max(s.RateLimit, 0)
+		}
+		if s.RateBurst != 0 {
+			existing.RateBurst = maxInt(s.RateBurst, 0)
+		}
+		if s.DailyQuota != 0 {
+			existing.DailyQuota = maxInt(s.DailyQuota, 0)
+		}
+		if s.MonthlyQuota != 0 {
+			existing.MonthlyQuota = maxInt(s.MonthlyQuota, 0)
+		}
 		if err = bucketSenders.Put(s.SenderUuid[:], existing.Bytes()); err != nil {
 			return fmt.Errorf("can't save sender: %v", err)
 		}
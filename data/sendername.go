@@ -0,0 +1,204 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// approval statuses of sender names
+const (
+	SenderNamePending  = "PENDING"
+	SenderNameApproved = "APPROVED"
+	SenderNameRejected = "REJECTED"
+)
+
+// SenderName is a sender ID registered for a sender, only approved ones can be used when the registry is enabled
+type SenderName struct {
+	SenderNameUuid uuid.UUID
+	SenderUuid     uuid.UUID
+	Name           string
+	Status         string
+	// Countries are ISO 3166-1 alpha-2 codes of destinations the name is allowed for, empty for any
+	Countries []string `json:",omitempty"`
+	Create    time.Time
+	Updated   time.Time
+}
+
+func (s *SenderName) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *SenderName) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate checks the status and country codes, upper-casing them
+func (s *SenderName) Validate() error {
+	if len(s.Name) == 0 {
+		return fmt.Errorf("name is empty")
+	}
+	s.Status = strings.ToUpper(s.Status)
+	switch s.Status {
+	case SenderNamePending, SenderNameApproved, SenderNameRejected:
+	default:
+		return fmt.Errorf("unknown status %s", s.Status)
+	}
+	for i, country := range s.Countries {
+		if len(country) != 2 {
+			return fmt.Errorf("bad country code %s", country)
+		}
+		s.Countries[i] = strings.ToUpper(country)
+	}
+	return nil
+}
+
+// AllowedIn reports whether the name can be used for destinations in the country
+func (s *SenderName) AllowedIn(country string) bool {
+	if len(s.Countries) == 0 {
+		return true
+	}
+	for _, c := range s.Countries {
+		if c == country {
+			return true
+		}
+	}
+	return false
+}
+
+// Save registers the name, a sender can't have the same name twice
+func (s *SenderName) Save(db *bbolt.DB) error {
+	s.SenderNameUuid = uuid.New()
+	s.Create = time.Now()
+	s.Updated = s.Create
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketSenders := tx.Bucket([]byte(BucketSenders))
+		if bucketSenders.Get(s.SenderUuid[:]) == nil {
+			return fmt.Errorf("sender not found")
+		}
+		bucketNames := tx.Bucket([]byte(BucketSenderNames))
+		err := bucketNames.ForEach(func(k, v []byte) error {
+			existing := &SenderName{}
+			if err := existing.FromBytes(v); err != nil {
+				return fmt.Errorf("can't parse sender name: %v, %s", err, string(v))
+			}
+			if existing.SenderUuid == s.SenderUuid && existing.Name == s.Name {
+				return fmt.Errorf("sender name %s is already registered", s.Name)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := bucketNames.Put(s.SenderNameUuid[:], s.Bytes()); err != nil {
+			return fmt.Errorf("can't save sender name: %v", err)
+		}
+		return nil
+	})
+}
+
+// Edit changes the status if it isn't empty and the countries if they aren't nil
+func (s *SenderName) Edit(db *bbolt.DB, id uuid.UUID) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketNames := tx.Bucket([]byte(BucketSenderNames))
+		bindata := bucketNames.Get(id[:])
+		if bindata == nil {
+			return fmt.Errorf("sender name not found")
+		}
+		existing := &SenderName{}
+		if err := existing.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse existing sender name data: %v %s", err, string(bindata))
+		}
+		if len(s.Status) > 0 {
+			existing.Status = s.Status
+		}
+		if s.Countries != nil {
+			existing.Countries = s.Countries
+		}
+		if err := existing.Validate(); err != nil {
+			return err
+		}
+		existing.Updated = time.Now()
+		*s = *existing
+		if err := bucketNames.Put(id[:], s.Bytes()); err != nil {
+			return fmt.Errorf("can't save sender name: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *SenderName) LoadById(db *bbolt.DB, id uuid.UUID) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		bindata := tx.Bucket([]byte(BucketSenderNames)).Get(id[:])
+		if bindata == nil {
+			return fmt.Errorf("sender name not found: %s", id.String())
+		}
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse sender name data: %v %s", err, string(bindata))
+		}
+		return nil
+	})
+}
+
+func (s *SenderName) Delete(db *bbolt.DB, id uuid.UUID) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketNames := tx.Bucket([]byte(BucketSenderNames))
+		if bucketNames.Get(id[:]) == nil {
+			return fmt.Errorf("sender name not found")
+		}
+		if err := bucketNames.Delete(id[:]); err != nil {
+			return fmt.Errorf("can't delete sender name: %v", err)
+		}
+		return nil
+	})
+}
+
+// List returns names registered for the sender sorted by name, of all senders if senderUuid is nil
+func (s *SenderName) List(db *bbolt.DB, senderUuid uuid.UUID) ([]*SenderName, error) {
+	ret := make([]*SenderName, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(BucketSenderNames)).ForEach(func(k, v []byte) error {
+			name := &SenderName{}
+			if err := name.FromBytes(v); err != nil {
+				return fmt.Errorf("can't parse sender name: %v, %s", err, string(v))
+			}
+			if senderUuid == uuid.Nil || name.SenderUuid == senderUuid {
+				ret = append(ret, name)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Name != ret[j].Name {
+			return ret[i].Name < ret[j].Name
+		}
+		return ret[i].Create.Before(ret[j].Create)
+	})
+	return ret, nil
+}
+
+// Find loads the name registered for the sender, found is false if there is none
+func (s *SenderName) Find(db *bbolt.DB, senderUuid uuid.UUID, name string) (found bool, err error) {
+	names, err := s.List(db, senderUuid)
+	if err != nil {
+		return false, err
+	}
+	for _, n := range names {
+		if n.Name == name {
+			*s = *n
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,156 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.etcd.io/bbolt"
+	"strings"
+	"time"
+)
+
+// line types of subscribers
+const (
+	LineMobile   = "MOBILE"
+	LineLandline = "LANDLINE"
+	LineVoip     = "VOIP"
+)
+
+// statuses of subscribers as an HLR reports them
+const (
+	SubscriberActive = "ACTIVE"
+	// SubscriberAbsent is switched off or out of coverage
+	SubscriberAbsent = "ABSENT"
+	// SubscriberUnknown is a number which isn't allocated to anybody
+	SubscriberUnknown = "UNKNOWN"
+)
+
+// Subscriber is a virtual subscriber of the number lookup
+type Subscriber struct {
+	// PhoneNumber is in E.164 format
+	PhoneNumber string
+	LineType    string
+	Status      string
+	// PortedTo is MCC and MNC of the carrier the number was ported to
+	PortedTo string `json:",omitempty"`
+	// Roaming is ISO 3166-1 alpha-2 code of the country the subscriber visits
+	Roaming string `json:",omitempty"`
+	Create  time.Time
+	Updated time.Time
+}
+
+func (s *Subscriber) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *Subscriber) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate checks the line type and the status upper-casing them, MOBILE and ACTIVE are the defaults
+func (s *Subscriber) Validate() error {
+	if !strings.HasPrefix(s.PhoneNumber, "+") {
+		return fmt.Errorf("phone number %s isn't in E.164 format", s.PhoneNumber)
+	}
+	s.LineType = strings.ToUpper(s.LineType)
+	switch s.LineType {
+	case "":
+		s.LineType = LineMobile
+	case LineMobile, LineLandline, LineVoip:
+	default:
+		return fmt.Errorf("unknown line type %s", s.LineType)
+	}
+	s.Status = strings.ToUpper(s.Status)
+	switch s.Status {
+	case "":
+		s.Status = SubscriberActive
+	case SubscriberActive, SubscriberAbsent, SubscriberUnknown:
+	default:
+		return fmt.Errorf("unknown status %s", s.Status)
+	}
+	s.Roaming = strings.ToUpper(s.Roaming)
+	if len(s.Roaming) > 0 && len(s.Roaming) != 2 {
+		return fmt.Errorf("bad country code %s", s.Roaming)
+	}
+	return nil
+}
+
+// Save creates or replaces the subscriber of the number keeping its creation time
+func (s *Subscriber) Save(db *bbolt.DB) error {
+	s.Updated = time.Now()
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketSubscribers := tx.Bucket([]byte(BucketSubscribers))
+		s.Create = s.Updated
+		if bindata := bucketSubscribers.Get([]byte(s.PhoneNumber)); bindata != nil {
+			existing := &Subscriber{}
+			if err := existing.FromBytes(bindata); err == nil {
+				s.Create = existing.Create
+			}
+		}
+		if err := bucketSubscribers.Put([]byte(s.PhoneNumber), s.Bytes()); err != nil {
+			return fmt.Errorf("can't save subscriber: %v", err)
+		}
+		return nil
+	})
+}
+
+// Load loads the subscriber of the E.164 number, found is false if the table has none
+func (s *Subscriber) Load(db *bbolt.DB, phoneNumber string) (found bool, err error) {
+	err = db.View(func(tx *bbolt.Tx) error {
+		bindata := tx.Bucket([]byte(BucketSubscribers)).Get([]byte(phoneNumber))
+		if bindata == nil {
+			return nil
+		}
+		found = true
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse subscriber data: %v %s", err, string(bindata))
+		}
+		return nil
+	})
+	return found, err
+}
+
+func (s *Subscriber) Delete(db *bbolt.DB, phoneNumber string) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketSubscribers := tx.Bucket([]byte(BucketSubscribers))
+		if bucketSubscribers.Get([]byte(phoneNumber)) == nil {
+			return fmt.Errorf("subscriber not found")
+		}
+		if err := bucketSubscribers.Delete([]byte(phoneNumber)); err != nil {
+			return fmt.Errorf("can't delete subscriber: %v", err)
+		}
+		return nil
+	})
+}
+
+// List returns subscribers sorted by number
+func (s *Subscriber) List(db *bbolt.DB, limit, offset int) ([]*Subscriber, error) {
+	ret := make([]*Subscriber, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		iterator := tx.Bucket([]byte(BucketSubscribers)).Cursor()
+		if limit == 0 {
+			limit = 10
+		}
+		limit += offset
+		i := 0
+		for k, v := iterator.First(); k != nil && i < limit; k, v = iterator.Next() {
+			i += 1
+			if i <= offset {
+				continue
+			}
+			subscriber := &Subscriber{}
+			if err := subscriber.FromBytes(v); err != nil {
+				return fmt.Errorf("can't parse subscriber: %v, %s", err, string(v))
+			}
+			ret = append(ret, subscriber)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,108 @@
+package data
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"time"
+)
+
+// quota periods
+const (
+	QuotaDaily   = "daily"
+	QuotaMonthly = "monthly"
+)
+
+// Usage is a number of sender's messages in the current UTC day and month
+type Usage struct {
+	Daily   int
+	Monthly int
+}
+
+func usageKeys(senderUuid uuid.UUID, now time.Time) ([]byte, []byte) {
+	now = now.UTC()
+	daily := append(append([]byte{}, senderUuid[:]...), now.Format("2006-01-02")...)
+	monthly := append(append([]byte{}, senderUuid[:]...), now.Format("2006-01")...)
+	return daily, monthly
+}
+
+func getCounter(bucket *bbolt.Bucket, key []byte) int {
+	value := bucket.Get(key)
+	if len(value) != 8 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(value))
+}
+
+func putCounter(bucket *bbolt.Bucket, key []byte, value int) error {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(value))
+	return bucket.Put(key, buf)
+}
+
+func (s *Usage) Load(db *bbolt.DB, senderUuid uuid.UUID, now time.Time) error {
+	daily, monthly := usageKeys(senderUuid, now)
+	return db.View(func(tx *bbolt.Tx) error {
+		bucketUsage := tx.Bucket([]byte(BucketUsage))
+		s.Daily = getCounter(bucketUsage, daily)
+		s.Monthly = getCounter(bucketUsage, monthly)
+		return nil
+	})
+}
+
+// Reserve counts one more message of the sender if it fits the quotas,
+// otherwise it returns the exceeded quota period
+func (s *Usage) Reserve(db *bbolt.DB, sender *Sender, now time.Time) (string, error) {
+	daily, monthly := usageKeys(sender.SenderUuid, now)
+	exceeded := ""
+	err := db.Update(func(tx *bbolt.Tx) error {
+		bucketUsage := tx.Bucket([]byte(BucketUsage))
+		s.Daily = getCounter(bucketUsage, daily)
+		s.Monthly = getCounter(bucketUsage, monthly)
+		if sender.DailyQuota > 0 && s.Daily >= sender.DailyQuota {
+			exceeded = QuotaDaily
+			return nil
+		}
+		if sender.MonthlyQuota > 0 && s.Monthly >= sender.MonthlyQuota {
+			exceeded = QuotaMonthly
+			return nil
+		}
+		s.Daily++
+		s.Monthly++
+		if err := putCounter(bucketUsage, daily, s.Daily); err != nil {
+			return fmt.Errorf("can't save daily usage: %v", err)
+		}
+		if err := putCounter(bucketUsage, monthly, s.Monthly); err != nil {
+			return fmt.Errorf("can't save monthly usage: %v", err)
+		}
+		return nil
+	})
+	return exceeded, err
+}
+
+// Release takes back the message counted by Reserve when it isn't accepted after all
+func (s *Usage) Release(db *bbolt.DB, sender *Sender, now time.Time) error {
+	daily, monthly := usageKeys(sender.SenderUuid, now)
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketUsage := tx.Bucket([]byte(BucketUsage))
+		s.Daily = maxInt(getCounter(bucketUsage, daily)-1, 0)
+		s.Monthly = maxInt(getCounter(bucketUsage, monthly)-1, 0)
+		if err := putCounter(bucketUsage, daily, s.Daily); err != nil {
+			return fmt.Errorf("can't save daily usage: %v", err)
+		}
+		if err := putCounter(bucketUsage, monthly, s.Monthly); err != nil {
+			return fmt.Errorf("can't save monthly usage: %v", err)
+		}
+		return nil
+	})
+}
+
+// QuotaReset returns time when the quota of the period is reset
+func QuotaReset(period string, now time.Time) time.Time {
+	now = now.UTC()
+	if period == QuotaMonthly {
+		return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
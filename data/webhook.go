@@ -0,0 +1,261 @@
+package data
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"time"
+)
+
+// Webhook is an outbound HTTP callback waiting for delivery or dead after all attempts
+type Webhook struct {
+	WebhookUuid uuid.UUID
+	SenderUuid  uuid.UUID
+	Method      string
+	Url         string
+	ContentType string
+	Headers     map[string]string
+	Body        string
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+	Create      time.Time
+	Died        time.Time
+}
+
+func (s *Webhook) Bytes() []byte {
+	bindata, _ := json.Marshal(s)
+	return bindata
+}
+
+func (s *Webhook) FromBytes(bindata []byte) error {
+	if err := json.Unmarshal(bindata, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// QueueKey is a key in the queue bucket, sorted by the time of the next attempt
+func (s *Webhook) QueueKey() []byte {
+	key := make([]byte, 8, 8+len(s.WebhookUuid))
+	binary.BigEndian.PutUint64(key, uint64(s.NextAttempt.UnixNano()))
+	return append(key, s.WebhookUuid[:]...)
+}
+
+func getWebhookBuckets(tx *bbolt.Tx) (bucketWebhooks, bucketQueue, bucketDead *bbolt.Bucket, err error) {
+	if bucketWebhooks = tx.Bucket([]byte(BucketWebhooks)); bucketWebhooks == nil {
+		err = fmt.Errorf("can't load bucket %s", BucketWebhooks)
+	}
+	if bucketQueue = tx.Bucket([]byte(BucketWebhookQueue)); bucketQueue == nil {
+		err = fmt.Errorf("can't load bucket %s", BucketWebhookQueue)
+	}
+	if bucketDead = tx.Bucket([]byte(BucketWebhookDeadLetters)); bucketDead == nil {
+		err = fmt.Errorf("can't load bucket %s", BucketWebhookDeadLetters)
+	}
+	return
+}
+
+// Save puts a new webhook to the queue for immediate delivery
+func (s *Webhook) Save(db *bbolt.DB) error {
+	s.WebhookUuid = uuid.New()
+	s.Create = time.Now()
+	s.NextAttempt = s.Create
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketWebhooks, bucketQueue, _, err := getWebhookBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return s.enqueue(bucketWebhooks, bucketQueue)
+	})
+}
+
+func (s *Webhook) enqueue(bucketWebhooks, bucketQueue *bbolt.Bucket) error {
+	if err := bucketWebhooks.Put(s.WebhookUuid[:], s.Bytes()); err != nil {
+		return fmt.Errorf("can't save webhook: %v", err)
+	}
+	if err := bucketQueue.Put(s.QueueKey(), s.WebhookUuid[:]); err != nil {
+		return fmt.Errorf("can't save webhook queue: %v", err)
+	}
+	return nil
+}
+
+func (s *Webhook) dequeue(bucketWebhooks, bucketQueue *bbolt.Bucket) error {
+	if err := bucketQueue.Delete(s.QueueKey()); err != nil {
+		return fmt.Errorf("can't delete webhook from queue: %v", err)
+	}
+	if err := bucketWebhooks.Delete(s.WebhookUuid[:]); err != nil {
+		return fmt.Errorf("can't delete webhook: %v", err)
+	}
+	return nil
+}
+
+// ListDue returns queued webhooks with the next attempt not after now
+func (s *Webhook) ListDue(db *bbolt.DB, now time.Time, limit int) ([]*Webhook, error) {
+	ret := make([]*Webhook, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		bucketWebhooks, bucketQueue, _, err := getWebhookBuckets(tx)
+		if err != nil {
+			return err
+		}
+		iterator := bucketQueue.Cursor()
+		for k, v := iterator.First(); k != nil && len(ret) < limit && int64(binary.BigEndian.Uint64(k)) <= now.UnixNano(); k, v = iterator.Next() {
+			hook, err := s.getWebhookFromBucket(bucketWebhooks, v)
+			if err != nil {
+				return err
+			}
+			ret = append(ret, hook)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	} else {
+		return ret, nil
+	}
+}
+
+// List returns webhooks waiting in the queue
+func (s *Webhook) List(db *bbolt.DB, limit, offset int) ([]*Webhook, error) {
+	return s.list(db, BucketWebhooks, limit, offset)
+}
+
+// ListDead returns webhooks from the dead letter bucket
+func (s *Webhook) ListDead(db *bbolt.DB, limit, offset int) ([]*Webhook, error) {
+	return s.list(db, BucketWebhookDeadLetters, limit, offset)
+}
+
+func (s *Webhook) list(db *bbolt.DB, bucket string, limit, offset int) ([]*Webhook, error) {
+	ret := make([]*Webhook, 0)
+	err := db.View(func(tx *bbolt.Tx) error {
+		bucketWebhooks := tx.Bucket([]byte(bucket))
+		if bucketWebhooks == nil {
+			return fmt.Errorf("can't load bucket %s", bucket)
+		}
+		iterator := bucketWebhooks.Cursor()
+		if limit == 0 {
+			limit = 10
+		}
+		limit += offset
+		i := 0
+		for k, v := iterator.First(); k != nil && i < limit; k, v = iterator.Next() {
+			i += 1
+			if i <= offset {
+				continue
+			}
+			hook := &Webhook{}
+			if err := hook.FromBytes(v); err != nil {
+				return fmt.Errorf("can't parse webhook: %v, %s", err, string(v))
+			}
+			ret = append(ret, hook)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	} else {
+		return ret, nil
+	}
+}
+
+func (s *Webhook) getWebhookFromBucket(bucketWebhooks *bbolt.Bucket, id []byte) (*Webhook, error) {
+	bindata := bucketWebhooks.Get(id)
+	if bindata == nil {
+		return nil, fmt.Errorf("can't find webhook by id %v", id)
+	}
+	hook := &Webhook{}
+	if err := hook.FromBytes(bindata); err != nil {
+		return nil, fmt.Errorf("can't parse webhook: %v, %s", err, string(bindata))
+	}
+	return hook, nil
+}
+
+// Delivered removes successfully sent webhook from the queue
+func (s *Webhook) Delivered(db *bbolt.DB) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketWebhooks, bucketQueue, _, err := getWebhookBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return s.dequeue(bucketWebhooks, bucketQueue)
+	})
+}
+
+// Retry records failed attempt and moves webhook in the queue to the next attempt time
+func (s *Webhook) Retry(db *bbolt.DB, lastError string, next time.Time) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketWebhooks, bucketQueue, _, err := getWebhookBuckets(tx)
+		if err != nil {
+			return err
+		}
+		if err := s.dequeue(bucketWebhooks, bucketQueue); err != nil {
+			return err
+		}
+		s.Attempts += 1
+		s.LastError = lastError
+		s.NextAttempt = next
+		return s.enqueue(bucketWebhooks, bucketQueue)
+	})
+}
+
+// Kill records failed attempt and moves webhook from the queue to dead letters
+func (s *Webhook) Kill(db *bbolt.DB, lastError string) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketWebhooks, bucketQueue, bucketDead, err := getWebhookBuckets(tx)
+		if err != nil {
+			return err
+		}
+		if err := s.dequeue(bucketWebhooks, bucketQueue); err != nil {
+			return err
+		}
+		s.Attempts += 1
+		s.LastError = lastError
+		s.Died = time.Now()
+		if err := bucketDead.Put(s.WebhookUuid[:], s.Bytes()); err != nil {
+			return fmt.Errorf("can't save dead webhook: %v", err)
+		}
+		return nil
+	})
+}
+
+// Replay moves dead webhook back to the queue with a fresh attempt counter
+func (s *Webhook) Replay(db *bbolt.DB, id uuid.UUID) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucketWebhooks, bucketQueue, bucketDead, err := getWebhookBuckets(tx)
+		if err != nil {
+			return err
+		}
+		bindata := bucketDead.Get(id[:])
+		if bindata == nil {
+			return fmt.Errorf("webhook not found: %s", id.String())
+		}
+		if err := s.FromBytes(bindata); err != nil {
+			return fmt.Errorf("can't parse webhook: %v, %s", err, string(bindata))
+		}
+		if err := bucketDead.Delete(id[:]); err != nil {
+			return fmt.Errorf("can't delete dead webhook: %v", err)
+		}
+		s.Attempts = 0
+		s.NextAttempt = time.Now()
+		s.Died = time.Time{}
+		return s.enqueue(bucketWebhooks, bucketQueue)
+	})
+}
+
+// DeleteDead removes webhook from dead letters
+func (s *Webhook) DeleteDead(db *bbolt.DB, id uuid.UUID) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		_, _, bucketDead, err := getWebhookBuckets(tx)
+		if err != nil {
+			return err
+		}
+		if bucketDead.Get(id[:]) == nil {
+			return fmt.Errorf("webhook not found: %s", id.String())
+		}
+		if err := bucketDead.Delete(id[:]); err != nil {
+			return fmt.Errorf("can't delete dead webhook: %v", err)
+		}
+		return nil
+	})
+}
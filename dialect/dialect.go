@@ -0,0 +1,254 @@
+package dialect
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// auth styles
+const (
+	AuthNone   = "none"
+	AuthBasic  = "basic"
+	AuthFields = "fields"
+	AuthHeader = "header"
+)
+
+// request formats
+const (
+	FormatJson  = "json"
+	FormatForm  = "form"
+	FormatQuery = "query"
+)
+
+// error kinds
+const (
+	ErrorAuth       = "auth"
+	ErrorValidation = "validation"
+	ErrorNotFound   = "notFound"
+	ErrorInternal   = "internal"
+	ErrorThrottled  = "throttled"
+	ErrorPayment    = "payment"
+)
+
+// Dialect describes request and response shapes of a provider's API
+type Dialect struct {
+	Name     string `yaml:"name"`
+	BasePath string `yaml:"basePath"`
+	Auth     Auth   `yaml:"auth"`
+	// Statuses maps message statuses to the provider's names, unmapped ones are used as is
+	Statuses map[string]string    `yaml:"statuses"`
+	Send     Endpoint             `yaml:"send"`
+	Status   *Endpoint            `yaml:"status"`
+	Callback *Callback            `yaml:"callback"`
+	Errors   map[string]*Response `yaml:"errors"`
+}
+
+// Auth tells where sender's login and password are
+type Auth struct {
+	Type string `yaml:"type"`
+	// Login and Password are request field names for fields auth or header names for header auth,
+	// for none auth Login is the sender's login all messages belong to
+	Login    string `yaml:"login"`
+	Password string `yaml:"password"`
+}
+
+// Endpoint is a request of the provider's API
+type Endpoint struct {
+	Method string `yaml:"method"`
+	Path   string `yaml:"path"`
+	Format string `yaml:"format"`
+	// Fields maps message fields (phone, text, sender, expiration, callback, id) to request field names
+	Fields   map[string]string `yaml:"fields"`
+	Response Response          `yaml:"response"`
+}
+
+// Response is a templated response body
+type Response struct {
+	Status      int    `yaml:"status"`
+	ContentType string `yaml:"contentType"`
+	Body        string `yaml:"body"`
+	template    *template.Template
+}
+
+// Callback is a templated status report sent to the message callback URL
+type Callback struct {
+	Method      string `yaml:"method"`
+	ContentType string `yaml:"contentType"`
+	Body        string `yaml:"body"`
+	template    *template.Template
+}
+
+// message fields of the request mapping
+const (
+	FieldPhone      = "phone"
+	FieldText       = "text"
+	FieldSender     = "sender"
+	FieldExpiration = "expiration"
+	FieldCallback   = "callback"
+	FieldId         = "id"
+)
+
+var defaultErrors = map[string]*Response{
+	ErrorAuth:       {Status: http.StatusUnauthorized, ContentType: "text/plain", Body: "{{.Error}}"},
+	ErrorValidation: {Status: http.StatusBadRequest, ContentType: "text/plain", Body: "{{.Error}}"},
+	ErrorNotFound:   {Status: http.StatusNotFound, ContentType: "text/plain", Body: "{{.Error}}"},
+	ErrorInternal:   {Status: http.StatusInternalServerError, ContentType: "text/plain", Body: "{{.Error}}"},
+	ErrorThrottled:  {Status: http.StatusTooManyRequests, ContentType: "text/plain", Body: "{{.Error}}"},
+	ErrorPayment:    {Status: http.StatusPaymentRequired, ContentType: "text/plain", Body: "{{.Error}}"},
+}
+
+// Load parses a dialect from YAML or JSON file
+func Load(path string) (*Dialect, error) {
+	bindata, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dialect{}
+	if err := yaml.UnmarshalStrict(bindata, d); err != nil {
+		return nil, fmt.Errorf("can't parse dialect %s: %v", path, err)
+	}
+	if err := d.init(); err != nil {
+		return nil, fmt.Errorf("bad dialect %s: %v", path, err)
+	}
+	return d, nil
+}
+
+// LoadDir loads all *.yaml, *.yml and *.json files of the directory sorted by name
+func LoadDir(dir string) ([]*Dialect, error) {
+	files := make([]string, 0)
+	for _, pattern := range []string{"*.yaml", "*.yml", "*.json"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	ret := make([]*Dialect, 0, len(files))
+	names := make(map[string]string)
+	for _, file := range files {
+		d, err := Load(file)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := names[d.Name]; ok {
+			return nil, fmt.Errorf("dialect %s is defined in %s and %s", d.Name, other, file)
+		}
+		names[d.Name] = file
+		ret = append(ret, d)
+	}
+	return ret, nil
+}
+
+// init checks the dialect, sets defaults and compiles templates
+func (d *Dialect) init() error {
+	if len(d.Name) == 0 {
+		return fmt.Errorf("name is required")
+	}
+	if !strings.HasPrefix(d.BasePath, "/") {
+		return fmt.Errorf("basePath must start with /")
+	}
+	d.BasePath = strings.TrimSuffix(d.BasePath, "/")
+	switch d.Auth.Type {
+	case AuthNone:
+		if len(d.Auth.Login) == 0 {
+			return fmt.Errorf("auth none requires sender's login")
+		}
+	case AuthBasic:
+	case AuthFields, AuthHeader:
+		if len(d.Auth.Login) == 0 || len(d.Auth.Password) == 0 {
+			return fmt.Errorf("auth %s requires login and password names", d.Auth.Type)
+		}
+	default:
+		return fmt.Errorf("unknown auth type %s", d.Auth.Type)
+	}
+	if err := d.Send.init("send", http.MethodPost, FormatJson); err != nil {
+		return err
+	}
+	for _, field := range []string{FieldPhone, FieldText} {
+		if len(d.Send.Fields[field]) == 0 {
+			return fmt.Errorf("send.fields.%s is required", field)
+		}
+	}
+	if d.Status != nil {
+		if err := d.Status.init("status", http.MethodGet, FormatQuery); err != nil {
+			return err
+		}
+		if len(d.Status.Fields[FieldId]) == 0 {
+			return fmt.Errorf("status.fields.id is required")
+		}
+	}
+	if d.Callback != nil {
+		if len(d.Callback.Method) == 0 {
+			d.Callback.Method = http.MethodPost
+		}
+		if len(d.Callback.ContentType) == 0 {
+			d.Callback.ContentType = "application/json"
+		}
+		tpl, err := parse(d.Name+".callback", d.Callback.Body)
+		if err != nil {
+			return err
+		}
+		d.Callback.template = tpl
+	}
+	if d.Errors == nil {
+		d.Errors = make(map[string]*Response)
+	}
+	for kind, def := range defaultErrors {
+		resp, ok := d.Errors[kind]
+		if !ok {
+			copied := *def
+			resp = &copied
+			d.Errors[kind] = resp
+		}
+		if err := resp.init(d.Name+".errors."+kind, def.Status); err != nil {
+			return err
+		}
+	}
+	for kind := range d.Errors {
+		if _, ok := defaultErrors[kind]; !ok {
+			return fmt.Errorf("unknown error kind %s", kind)
+		}
+	}
+	return nil
+}
+
+func (e *Endpoint) init(name, method, format string) error {
+	if len(e.Method) == 0 {
+		e.Method = method
+	}
+	e.Method = strings.ToUpper(e.Method)
+	if len(e.Format) == 0 {
+		e.Format = format
+	}
+	switch e.Format {
+	case FormatJson, FormatForm, FormatQuery:
+	default:
+		return fmt.Errorf("%s: unknown format %s", name, e.Format)
+	}
+	if !strings.HasPrefix(e.Path, "/") {
+		return fmt.Errorf("%s: path must start with /", name)
+	}
+	return e.Response.init(name+".response", http.StatusOK)
+}
+
+func (r *Response) init(name string, status int) error {
+	if r.Status == 0 {
+		r.Status = status
+	}
+	if len(r.ContentType) == 0 {
+		r.ContentType = "application/json"
+	}
+	tpl, err := parse(name, r.Body)
+	if err != nil {
+		return err
+	}
+	r.template = tpl
+	return nil
+}
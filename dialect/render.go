@@ -0,0 +1,112 @@
+package dialect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"smsgate-mock/data"
+	"text/template"
+	"time"
+)
+
+var funcs = template.FuncMap{
+	// json quotes a value as JSON
+	"json": func(v interface{}) (string, error) {
+		bindata, err := json.Marshal(v)
+		return string(bindata), err
+	},
+	"unix": func(t time.Time) int64 {
+		if t.IsZero() {
+			return 0
+		}
+		return t.Unix()
+	},
+	"date": func(layout string, t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(layout)
+	},
+}
+
+func parse(name, body string) (*template.Template, error) {
+	tpl, err := template.New(name).Funcs(funcs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse template %s: %v", name, err)
+	}
+	return tpl, nil
+}
+
+// View is data of response and callback templates
+type View struct {
+	Id        string
+	Status    string
+	RawStatus string
+	Final     bool
+	Phone     string
+	Text      string
+	Sender    string
+	Created   time.Time
+	Updated   time.Time
+	Done      time.Time
+	Expires   time.Time
+	Encoding  string
+	Segments  int
+	// ErrorCode and ErrorMessage explain a failed final status
+	ErrorCode    int
+	ErrorMessage string
+	// Error and Field describe failed request
+	Error string
+	Field string
+}
+
+// View makes template data of the message with statuses in the dialect's vocabulary
+func (d *Dialect) View(msg *data.Message) *View {
+	return &View{
+		Id:           msg.MessageUuid.String(),
+		Status:       d.StatusName(msg.Status),
+		RawStatus:    msg.Status,
+		Final:        data.IsFinalStatus(msg.Status),
+		Phone:        msg.PhoneNumber,
+		Text:         msg.MessageText,
+		Sender:       msg.SenderName,
+		Created:      msg.Create,
+		Updated:      msg.Updated,
+		Done:         msg.Done,
+		Expires:      msg.Expires,
+		Encoding:     msg.Encoding,
+		Segments:     msg.Segments,
+		ErrorCode:    msg.ErrorCode,
+		ErrorMessage: msg.ErrorMessage,
+	}
+}
+
+// ErrorView makes template data of failed request
+func (d *Dialect) ErrorView(field string, err error) *View {
+	return &View{Error: err.Error(), Field: field}
+}
+
+func (d *Dialect) StatusName(status string) string {
+	if name, ok := d.Statuses[status]; ok {
+		return name
+	}
+	return status
+}
+
+// Render executes the response template
+func (r *Response) Render(view *View) ([]byte, error) {
+	return execute(r.template, view)
+}
+
+// Render executes the callback template
+func (c *Callback) Render(view *View) ([]byte, error) {
+	return execute(c.template, view)
+}
+
+func execute(tpl *template.Template, view *View) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, view); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
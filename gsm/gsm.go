@@ -0,0 +1,94 @@
+package gsm
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// encodings
+const (
+	GSM7 = "GSM7"
+	UCS2 = "UCS2"
+)
+
+// limits of a single message and of a part of concatenated one, in septets for GSM-7 and UTF-16 units for UCS-2
+const (
+	GSM7Single = 160
+	GSM7Part   = 153
+	UCS2Single = 70
+	UCS2Part   = 67
+)
+
+// basic is GSM 03.38 default alphabet without the escape character
+const basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// extended characters take two septets: the escape and the character
+const extended = "\f^{}\\[~]|€"
+
+// Septets returns the number of septets of the rune, 0 if it isn't in GSM 03.38
+func Septets(r rune) int {
+	if strings.ContainsRune(basic, r) {
+		return 1
+	}
+	if strings.ContainsRune(extended, r) {
+		return 2
+	}
+	return 0
+}
+
+// IsGSM7 reports whether the text can be sent in GSM-7
+func IsGSM7(text string) bool {
+	for _, r := range text {
+		if Septets(r) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Encoding returns GSM7 if the text fits GSM 03.38 with the extension table, UCS2 otherwise
+func Encoding(text string) string {
+	if IsGSM7(text) {
+		return GSM7
+	}
+	return UCS2
+}
+
+// Split returns encoding of the text and its parts as they are sent, empty text has no parts.
+// Escape sequences and surrogate pairs are never split between parts
+func Split(text string) (string, []string) {
+	encoding := Encoding(text)
+	if len(text) == 0 {
+		return encoding, []string{}
+	}
+	size := Septets
+	single, part := GSM7Single, GSM7Part
+	if encoding == UCS2 {
+		size = func(r rune) int {
+			return len(utf16.Encode([]rune{r}))
+		}
+		single, part = UCS2Single, UCS2Part
+	}
+	total := 0
+	for _, r := range text {
+		total += size(r)
+	}
+	if total <= single {
+		return encoding, []string{text}
+	}
+	parts := make([]string, 0, total/part+1)
+	var current strings.Builder
+	length := 0
+	for _, r := range text {
+		n := size(r)
+		if length+n > part {
+			parts = append(parts, current.String())
+			current.Reset()
+			length = 0
+		}
+		current.WriteRune(r)
+		length += n
+	}
+	return encoding, append(parts, current.String())
+}
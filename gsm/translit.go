@@ -0,0 +1,72 @@
+package gsm
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// translit maps characters outside GSM 03.38 to GSM-7 lookalikes
+var translit = map[rune]string{
+	// Cyrillic, Russian with Ukrainian and Belarusian letters
+	'А': "A", 'Б': "B", 'В': "V", 'Г': "G", 'Д': "D", 'Е': "E", 'Ё': "Yo", 'Ж': "Zh", 'З': "Z", 'И': "I",
+	'Й': "Y", 'К': "K", 'Л': "L", 'М': "M", 'Н': "N", 'О': "O", 'П': "P", 'Р': "R", 'С': "S", 'Т': "T",
+	'У': "U", 'Ф': "F", 'Х': "Kh", 'Ц': "Ts", 'Ч': "Ch", 'Ш': "Sh", 'Щ': "Shch", 'Ъ': "", 'Ы': "Y", 'Ь': "",
+	'Э': "E", 'Ю': "Yu", 'Я': "Ya", 'Є': "Ye", 'І': "I", 'Ї': "Yi", 'Ґ': "G", 'Ў': "U",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u",
+	// Greek capitals missing in GSM 03.38 look like Latin ones, small letters become capitals
+	'Α': "A", 'Β': "B", 'Ε': "E", 'Ζ': "Z", 'Η': "H", 'Ι': "I", 'Κ': "K", 'Μ': "M", 'Ν': "N", 'Ο': "O",
+	'Ρ': "P", 'Τ': "T", 'Υ': "Y", 'Χ': "X",
+	'α': "A", 'β': "B", 'γ': "Γ", 'δ': "Δ", 'ε': "E", 'ζ': "Z", 'η': "H", 'θ': "Θ", 'ι': "I", 'κ': "K",
+	'λ': "Λ", 'μ': "M", 'ν': "N", 'ξ': "Ξ", 'ο': "O", 'π': "Π", 'ρ': "P", 'σ': "Σ", 'ς': "Σ", 'τ': "T",
+	'υ': "Y", 'φ': "Φ", 'χ': "X", 'ψ': "Ψ", 'ω': "Ω",
+	// Latin letters without a base letter
+	'Æ': "Æ", 'Ø': "Ø", 'Đ': "D", 'đ': "d", 'Ł': "L", 'ł': "l", 'Œ': "OE", 'œ': "oe", 'Þ': "Th", 'þ': "th",
+	'ð': "d", 'ı': "i",
+	// typography
+	'‘': "'", '’': "'", '‚': "'", '‛': "'", '“': "\"", '”': "\"", '„': "\"", '«': "\"", '»': "\"",
+	'–': "-", '—': "-", '−': "-", '…': "...", '•': "*", '·': ".", ' ': " ", '\t': " ", '№': "No",
+}
+
+// Transliterate replaces characters outside GSM 03.38 with lookalikes, accents are dropped unless
+// the accented letter is in GSM 03.38, characters without a replacement become "?"
+func Transliterate(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if Septets(r) > 0 {
+			b.WriteRune(r)
+			continue
+		}
+		if s, ok := translit[r]; ok {
+			b.WriteString(s)
+			continue
+		}
+		if s, ok := stripAccents(r); ok {
+			b.WriteString(s)
+			continue
+		}
+		b.WriteRune('?')
+	}
+	return b.String()
+}
+
+// stripAccents decomposes the rune and drops combining marks, ok is false if the rest can't be transliterated
+func stripAccents(r rune) (string, bool) {
+	var b strings.Builder
+	for _, c := range norm.NFD.String(string(r)) {
+		if unicode.Is(unicode.Mn, c) {
+			continue
+		}
+		if Septets(c) > 0 {
+			b.WriteRune(c)
+		} else if s, ok := translit[c]; ok {
+			b.WriteString(s)
+		} else {
+			return "", false
+		}
+	}
+	return b.String(), b.Len() > 0
+}
@@ -0,0 +1,97 @@
+package phone
+
+import "strings"
+
+// Carrier is a mobile network owning a range of numbers
+type Carrier struct {
+	// Prefix is international number digits without +
+	Prefix  string
+	Country string
+	Mcc     string
+	Mnc     string
+	Name    string
+}
+
+// Id is MCC and MNC of the carrier
+func (c *Carrier) Id() string {
+	return c.Mcc + c.Mnc
+}
+
+// Carriers is a table of number ranges as they were originally allocated, ported numbers aren't known
+var Carriers = []Carrier{
+	{Prefix: "7900", Country: "RU", Mcc: "250", Mnc: "20", Name: "Tele2"},
+	{Prefix: "7901", Country: "RU", Mcc: "250", Mnc: "20", Name: "Tele2"},
+	{Prefix: "7902", Country: "RU", Mcc: "250", Mnc: "20", Name: "Tele2"},
+	{Prefix: "7903", Country: "RU", Mcc: "250", Mnc: "99", Name: "Beeline"},
+	{Prefix: "7904", Country: "RU", Mcc: "250", Mnc: "20", Name: "Tele2"},
+	{Prefix: "7905", Country: "RU", Mcc: "250", Mnc: "99", Name: "Beeline"},
+	{Prefix: "7906", Country: "RU", Mcc: "250", Mnc: "99", Name: "Beeline"},
+	{Prefix: "7908", Country: "RU", Mcc: "250", Mnc: "20", Name: "Tele2"},
+	{Prefix: "7909", Country: "RU", Mcc: "250", Mnc: "99", Name: "Beeline"},
+	{Prefix: "791", Country: "RU", Mcc: "250", Mnc: "01", Name: "MTS"},
+	{Prefix: "792", Country: "RU", Mcc: "250", Mnc: "02", Name: "MegaFon"},
+	{Prefix: "7950", Country: "RU", Mcc: "250", Mnc: "20", Name: "Tele2"},
+	{Prefix: "7951", Country: "RU", Mcc: "250", Mnc: "20", Name: "Tele2"},
+	{Prefix: "7952", Country: "RU", Mcc: "250", Mnc: "20", Name: "Tele2"},
+	{Prefix: "7953", Country: "RU", Mcc: "250", Mnc: "20", Name: "Tele2"},
+	{Prefix: "796", Country: "RU", Mcc: "250", Mnc: "99", Name: "Beeline"},
+	{Prefix: "7977", Country: "RU", Mcc: "250", Mnc: "20", Name: "Tele2"},
+	{Prefix: "798", Country: "RU", Mcc: "250", Mnc: "01", Name: "MTS"},
+	{Prefix: "7700", Country: "KZ", Mcc: "401", Mnc: "07", Name: "Altel"},
+	{Prefix: "7701", Country: "KZ", Mcc: "401", Mnc: "02", Name: "Kcell"},
+	{Prefix: "7702", Country: "KZ", Mcc: "401", Mnc: "02", Name: "Kcell"},
+	{Prefix: "7705", Country: "KZ", Mcc: "401", Mnc: "01", Name: "Beeline KZ"},
+	{Prefix: "7707", Country: "KZ", Mcc: "401", Mnc: "77", Name: "Tele2 KZ"},
+	{Prefix: "7747", Country: "KZ", Mcc: "401", Mnc: "77", Name: "Tele2 KZ"},
+	{Prefix: "7777", Country: "KZ", Mcc: "401", Mnc: "01", Name: "Beeline KZ"},
+	{Prefix: "38050", Country: "UA", Mcc: "255", Mnc: "01", Name: "Vodafone UA"},
+	{Prefix: "38066", Country: "UA", Mcc: "255", Mnc: "01", Name: "Vodafone UA"},
+	{Prefix: "38095", Country: "UA", Mcc: "255", Mnc: "01", Name: "Vodafone UA"},
+	{Prefix: "38099", Country: "UA", Mcc: "255", Mnc: "01", Name: "Vodafone UA"},
+	{Prefix: "38067", Country: "UA", Mcc: "255", Mnc: "03", Name: "Kyivstar"},
+	{Prefix: "38068", Country: "UA", Mcc: "255", Mnc: "03", Name: "Kyivstar"},
+	{Prefix: "38096", Country: "UA", Mcc: "255", Mnc: "03", Name: "Kyivstar"},
+	{Prefix: "38097", Country: "UA", Mcc: "255", Mnc: "03", Name: "Kyivstar"},
+	{Prefix: "38098", Country: "UA", Mcc: "255", Mnc: "03", Name: "Kyivstar"},
+	{Prefix: "38063", Country: "UA", Mcc: "255", Mnc: "06", Name: "lifecell"},
+	{Prefix: "38073", Country: "UA", Mcc: "255", Mnc: "06", Name: "lifecell"},
+	{Prefix: "38093", Country: "UA", Mcc: "255", Mnc: "06", Name: "lifecell"},
+	{Prefix: "49151", Country: "DE", Mcc: "262", Mnc: "01", Name: "Telekom"},
+	{Prefix: "49160", Country: "DE", Mcc: "262", Mnc: "01", Name: "Telekom"},
+	{Prefix: "49170", Country: "DE", Mcc: "262", Mnc: "01", Name: "Telekom"},
+	{Prefix: "49171", Country: "DE", Mcc: "262", Mnc: "01", Name: "Telekom"},
+	{Prefix: "49175", Country: "DE", Mcc: "262", Mnc: "01", Name: "Telekom"},
+	{Prefix: "49152", Country: "DE", Mcc: "262", Mnc: "02", Name: "Vodafone"},
+	{Prefix: "49162", Country: "DE", Mcc: "262", Mnc: "02", Name: "Vodafone"},
+	{Prefix: "49172", Country: "DE", Mcc: "262", Mnc: "02", Name: "Vodafone"},
+	{Prefix: "49173", Country: "DE", Mcc: "262", Mnc: "02", Name: "Vodafone"},
+	{Prefix: "49174", Country: "DE", Mcc: "262", Mnc: "02", Name: "Vodafone"},
+	{Prefix: "49157", Country: "DE", Mcc: "262", Mnc: "03", Name: "O2"},
+	{Prefix: "49159", Country: "DE", Mcc: "262", Mnc: "03", Name: "O2"},
+	{Prefix: "49163", Country: "DE", Mcc: "262", Mnc: "03", Name: "O2"},
+	{Prefix: "49176", Country: "DE", Mcc: "262", Mnc: "03", Name: "O2"},
+	{Prefix: "49177", Country: "DE", Mcc: "262", Mnc: "03", Name: "O2"},
+	{Prefix: "49178", Country: "DE", Mcc: "262", Mnc: "03", Name: "O2"},
+	{Prefix: "49179", Country: "DE", Mcc: "262", Mnc: "03", Name: "O2"},
+}
+
+// LookupCarrier returns the carrier with the longest prefix of the digits, nil if there is none
+func LookupCarrier(digits string) *Carrier {
+	var ret *Carrier
+	for i := range Carriers {
+		if strings.HasPrefix(digits, Carriers[i].Prefix) && (ret == nil || len(Carriers[i].Prefix) > len(ret.Prefix)) {
+			ret = &Carriers[i]
+		}
+	}
+	return ret
+}
+
+// CarrierById returns the first carrier with MCC and MNC, nil if there is none
+func CarrierById(id string) *Carrier {
+	for i := range Carriers {
+		if Carriers[i].Id() == id {
+			return &Carriers[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package phone
+
+import (
+	"fmt"
+	"strings"
+)
+
+// E164MaxDigits is the maximum number of digits of an international number including the country code
+const E164MaxDigits = 15
+
+// Plan is a simplified numbering plan of a country
+type Plan struct {
+	// Country is ISO 3166-1 alpha-2 code
+	Country string
+	// Code is the country calling code
+	Code string
+	// Trunk is the national prefix dropped in international format
+	Trunk string
+	// MinLength and MaxLength of the national significant number
+	MinLength int
+	MaxLength int
+}
+
+// Plans are the known numbering plans, the first one of a shared calling code is used for international numbers
+var Plans = []Plan{
+	{Country: "US", Code: "1", Trunk: "1", MinLength: 10, MaxLength: 10},
+	{Country: "CA", Code: "1", Trunk: "1", MinLength: 10, MaxLength: 10},
+	{Country: "RU", Code: "7", Trunk: "8", MinLength: 10, MaxLength: 10},
+	{Country: "KZ", Code: "7", Trunk: "8", MinLength: 10, MaxLength: 10},
+	{Country: "EG", Code: "20", Trunk: "0", MinLength: 9, MaxLength: 10},
+	{Country: "ZA", Code: "27", Trunk: "0", MinLength: 9, MaxLength: 9},
+	{Country: "GR", Code: "30", MinLength: 10, MaxLength: 10},
+	{Country: "NL", Code: "31", Trunk: "0", MinLength: 9, MaxLength: 9},
+	{Country: "BE", Code: "32", Trunk: "0", MinLength: 8, MaxLength: 9},
+	{Country: "FR", Code: "33", Trunk: "0", MinLength: 9, MaxLength: 9},
+	{Country: "ES", Code: "34", MinLength: 9, MaxLength: 9},
+	{Country: "IT", Code: "39", MinLength: 6, MaxLength: 11},
+	{Country: "CH", Code: "41", Trunk: "0", MinLength: 9, MaxLength: 9},
+	{Country: "AT", Code: "43", Trunk: "0", MinLength: 4, MaxLength: 13},
+	{Country: "GB", Code: "44", Trunk: "0", MinLength: 9, MaxLength: 10},
+	{Country: "SE", Code: "46", Trunk: "0", MinLength: 7, MaxLength: 9},
+	{Country: "PL", Code: "48", MinLength: 9, MaxLength: 9},
+	{Country: "DE", Code: "49", Trunk: "0", MinLength: 6, MaxLength: 13},
+	{Country: "MX", Code: "52", MinLength: 10, MaxLength: 10},
+	{Country: "BR", Code: "55", Trunk: "0", MinLength: 10, MaxLength: 11},
+	{Country: "AU", Code: "61", Trunk: "0", MinLength: 9, MaxLength: 9},
+	{Country: "ID", Code: "62", Trunk: "0", MinLength: 8, MaxLength: 12},
+	{Country: "SG", Code: "65", MinLength: 8, MaxLength: 8},
+	{Country: "JP", Code: "81", Trunk: "0", MinLength: 9, MaxLength: 10},
+	{Country: "KR", Code: "82", Trunk: "0", MinLength: 8, MaxLength: 10},
+	{Country: "CN", Code: "86", Trunk: "0", MinLength: 9, MaxLength: 11},
+	{Country: "TR", Code: "90", Trunk: "0", MinLength: 10, MaxLength: 10},
+	{Country: "IN", Code: "91", Trunk: "0", MinLength: 10, MaxLength: 10},
+	{Country: "NG", Code: "234", Trunk: "0", MinLength: 8, MaxLength: 10},
+	{Country: "PT", Code: "351", MinLength: 9, MaxLength: 9},
+	{Country: "UA", Code: "380", Trunk: "0", MinLength: 9, MaxLength: 9},
+	{Country: "CZ", Code: "420", MinLength: 9, MaxLength: 9},
+	{Country: "AE", Code: "971", Trunk: "0", MinLength: 8, MaxLength: 9},
+	{Country: "IL", Code: "972", Trunk: "0", MinLength: 8, MaxLength: 9},
+}
+
+// PlanOf returns the numbering plan of the country, nil if it's unknown
+func PlanOf(country string) *Plan {
+	country = strings.ToUpper(country)
+	for i := range Plans {
+		if Plans[i].Country == country {
+			return &Plans[i]
+		}
+	}
+	return nil
+}
+
+// PlanByCode returns the numbering plan with the longest calling code the digits start with, nil if there is none
+func PlanByCode(digits string) *Plan {
+	var ret *Plan
+	for i := range Plans {
+		if strings.HasPrefix(digits, Plans[i].Code) && (ret == nil || len(Plans[i].Code) > len(ret.Code)) {
+			ret = &Plans[i]
+		}
+	}
+	return ret
+}
+
+func (p *Plan) fits(nsn string) bool {
+	return len(nsn) >= p.MinLength && len(nsn) <= p.MaxLength
+}
+
+// Normalize converts the number to E.164, numbers without + or 00 are read in the numbering plan of the country:
+// with the trunk prefix, with the calling code or as a national significant number
+func Normalize(number, country string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')', '.':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(number))
+	if digits == "" {
+		return "", fmt.Errorf("number is empty")
+	}
+	international := false
+	if strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+		international = true
+	} else if strings.HasPrefix(digits, "00") {
+		digits = digits[2:]
+		international = true
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("number has invalid character %q", r)
+		}
+	}
+	if international {
+		return international164(digits)
+	}
+	plan := PlanOf(country)
+	if plan == nil {
+		return "", fmt.Errorf("number %s has no country code and country %q is unknown", number, country)
+	}
+	if plan.Trunk != "" && strings.HasPrefix(digits, plan.Trunk) && plan.fits(digits[len(plan.Trunk):]) {
+		return "+" + plan.Code + digits[len(plan.Trunk):], nil
+	}
+	if strings.HasPrefix(digits, plan.Code) && plan.fits(digits[len(plan.Code):]) {
+		return "+" + digits, nil
+	}
+	if plan.fits(digits) {
+		return "+" + plan.Code + digits, nil
+	}
+	return "", fmt.Errorf("number %s doesn't fit %s numbering plan", number, plan.Country)
+}
+
+// international164 checks digits of an international number
+func international164(digits string) (string, error) {
+	if len(digits) > E164MaxDigits {
+		return "", fmt.Errorf("number has more than %d digits", E164MaxDigits)
+	}
+	if strings.HasPrefix(digits, "0") {
+		return "", fmt.Errorf("country code can't start with 0")
+	}
+	plan := PlanByCode(digits)
+	if plan == nil {
+		// unknown country, only the length is checked
+		if len(digits) < 8 {
+			return "", fmt.Errorf("number is too short")
+		}
+		return "+" + digits, nil
+	}
+	if !plan.fits(digits[len(plan.Code):]) {
+		return "", fmt.Errorf("number doesn't fit %s numbering plan", plan.Country)
+	}
+	return "+" + digits, nil
+}
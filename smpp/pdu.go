@@ -0,0 +1,377 @@
+package smpp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// command ids
+const (
+	GenericNack         uint32 = 0x80000000
+	BindReceiver        uint32 = 0x00000001
+	BindReceiverResp    uint32 = 0x80000001
+	BindTransmitter     uint32 = 0x00000002
+	BindTransmitterResp uint32 = 0x80000002
+	QuerySm             uint32 = 0x00000003
+	QuerySmResp         uint32 = 0x80000003
+	SubmitSm            uint32 = 0x00000004
+	SubmitSmResp        uint32 = 0x80000004
+	DeliverSm           uint32 = 0x00000005
+	DeliverSmResp       uint32 = 0x80000005
+	Unbind              uint32 = 0x00000006
+	UnbindResp          uint32 = 0x80000006
+	BindTransceiver     uint32 = 0x00000009
+	BindTransceiverResp uint32 = 0x80000009
+	EnquireLink         uint32 = 0x00000015
+	EnquireLinkResp     uint32 = 0x80000015
+)
+
+// command statuses
+const (
+	StatusOk         uint32 = 0x00
+	StatusInvMsgLen  uint32 = 0x01
+	StatusInvCmdLen  uint32 = 0x02
+	StatusInvCmdId   uint32 = 0x03
+	StatusInvBndSts  uint32 = 0x04
+	StatusAlyBnd     uint32 = 0x05
+	StatusSysErr     uint32 = 0x08
+	StatusInvSrcAdr  uint32 = 0x0A
+	StatusInvDstAdr  uint32 = 0x0B
+	StatusInvMsgId   uint32 = 0x0C
+	StatusBindFail   uint32 = 0x0D
+	StatusInvPaswd   uint32 = 0x0E
+	StatusInvSysId   uint32 = 0x0F
+	StatusMsgQFul    uint32 = 0x14
+	StatusSubmitFail uint32 = 0x45
+	StatusThrottled  uint32 = 0x58
+	StatusQueryFail  uint32 = 0x67
+)
+
+// message states of query_sm_resp and message_state TLV
+const (
+	StateEnroute       byte = 1
+	StateDelivered     byte = 2
+	StateExpired       byte = 3
+	StateDeleted       byte = 4
+	StateUndeliverable byte = 5
+	StateAccepted      byte = 6
+	StateUnknown       byte = 7
+	StateRejected      byte = 8
+)
+
+// optional parameter tags
+const (
+	TagReceiptedMessageId uint16 = 0x001E
+	TagMessagePayload     uint16 = 0x0424
+	TagMessageState       uint16 = 0x0427
+)
+
+// data codings
+const (
+	CodingDefault byte = 0x00
+	CodingLatin1  byte = 0x03
+	CodingUcs2    byte = 0x08
+)
+
+// EsmClassReceipt marks deliver_sm as a delivery receipt
+const EsmClassReceipt byte = 0x04
+
+// responseMask is set in command id of responses
+const responseMask uint32 = 0x80000000
+
+const headerLen = 16
+const maxPduLen = 64 * 1024
+
+type PDU struct {
+	CommandId uint32
+	Status    uint32
+	Sequence  uint32
+	Body      []byte
+}
+
+// ReadPDU reads one PDU from the stream
+func ReadPDU(r io.Reader) (*PDU, error) {
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	if length < headerLen || length > maxPduLen {
+		return nil, fmt.Errorf("bad command length %d", length)
+	}
+	p := &PDU{
+		CommandId: binary.BigEndian.Uint32(header[4:]),
+		Status:    binary.BigEndian.Uint32(header[8:]),
+		Sequence:  binary.BigEndian.Uint32(header[12:]),
+		Body:      make([]byte, length-headerLen),
+	}
+	if _, err := io.ReadFull(r, p.Body); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *PDU) Bytes() []byte {
+	buf := make([]byte, headerLen, headerLen+len(p.Body))
+	binary.BigEndian.PutUint32(buf, uint32(headerLen+len(p.Body)))
+	binary.BigEndian.PutUint32(buf[4:], p.CommandId)
+	binary.BigEndian.PutUint32(buf[8:], p.Status)
+	binary.BigEndian.PutUint32(buf[12:], p.Sequence)
+	return append(buf, p.Body...)
+}
+
+// Response makes a response PDU for the request
+func (p *PDU) Response(status uint32, body []byte) *PDU {
+	return &PDU{CommandId: p.CommandId | responseMask, Status: status, Sequence: p.Sequence, Body: body}
+}
+
+// Writer builds PDU body
+type Writer struct {
+	bytes.Buffer
+}
+
+func (w *Writer) CString(s string) {
+	w.WriteString(s)
+	w.WriteByte(0)
+}
+
+func (w *Writer) TLV(tag uint16, value []byte) {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint16(buf, tag)
+	binary.BigEndian.PutUint16(buf[2:], uint16(len(value)))
+	w.Write(buf)
+	w.Write(value)
+}
+
+// Reader parses PDU body, the first error is kept and makes all subsequent reads return zero values
+type Reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func NewReader(data []byte) *Reader {
+	return &Reader{data: data}
+}
+
+func (r *Reader) Err() error {
+	return r.err
+}
+
+func (r *Reader) CString() string {
+	if r.err != nil {
+		return ""
+	}
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		r.err = fmt.Errorf("unterminated string at %d", r.pos)
+		return ""
+	}
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+	return s
+}
+
+func (r *Reader) Byte() byte {
+	b := r.Bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *Reader) Bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if r.pos+n > len(r.data) {
+		r.err = fmt.Errorf("unexpected end of body at %d", r.pos)
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// TLVs reads optional parameters till the end of the body
+func (r *Reader) TLVs() map[uint16][]byte {
+	ret := make(map[uint16][]byte)
+	for r.err == nil && r.pos+4 <= len(r.data) {
+		head := r.Bytes(4)
+		value := r.Bytes(int(binary.BigEndian.Uint16(head[2:])))
+		if r.err == nil {
+			ret[binary.BigEndian.Uint16(head)] = value
+		}
+	}
+	return ret
+}
+
+// Bind is a body of bind_receiver, bind_transmitter and bind_transceiver
+type Bind struct {
+	SystemId         string
+	Password         string
+	SystemType       string
+	InterfaceVersion byte
+	AddrTon          byte
+	AddrNpi          byte
+	AddressRange     string
+}
+
+func (s *Bind) Decode(body []byte) error {
+	r := NewReader(body)
+	s.SystemId = r.CString()
+	s.Password = r.CString()
+	s.SystemType = r.CString()
+	s.InterfaceVersion = r.Byte()
+	s.AddrTon = r.Byte()
+	s.AddrNpi = r.Byte()
+	s.AddressRange = r.CString()
+	return r.Err()
+}
+
+func (s *Bind) Encode() []byte {
+	w := &Writer{}
+	w.CString(s.SystemId)
+	w.CString(s.Password)
+	w.CString(s.SystemType)
+	w.WriteByte(s.InterfaceVersion)
+	w.WriteByte(s.AddrTon)
+	w.WriteByte(s.AddrNpi)
+	w.CString(s.AddressRange)
+	return w.Bytes()
+}
+
+// ShortMessage is a body of submit_sm and deliver_sm
+type ShortMessage struct {
+	ServiceType          string
+	SourceTon            byte
+	SourceNpi            byte
+	Source               string
+	DestTon              byte
+	DestNpi              byte
+	Destination          string
+	EsmClass             byte
+	ProtocolId           byte
+	PriorityFlag         byte
+	ScheduleDeliveryTime string
+	ValidityPeriod       string
+	RegisteredDelivery   byte
+	ReplaceIfPresent     byte
+	DataCoding           byte
+	SmDefaultMsgId       byte
+	Message              []byte
+	TLVs                 map[uint16][]byte
+}
+
+func (s *ShortMessage) Decode(body []byte) error {
+	r := NewReader(body)
+	s.ServiceType = r.CString()
+	s.SourceTon = r.Byte()
+	s.SourceNpi = r.Byte()
+	s.Source = r.CString()
+	s.DestTon = r.Byte()
+	s.DestNpi = r.Byte()
+	s.Destination = r.CString()
+	s.EsmClass = r.Byte()
+	s.ProtocolId = r.Byte()
+	s.PriorityFlag = r.Byte()
+	s.ScheduleDeliveryTime = r.CString()
+	s.ValidityPeriod = r.CString()
+	s.RegisteredDelivery = r.Byte()
+	s.ReplaceIfPresent = r.Byte()
+	s.DataCoding = r.Byte()
+	s.SmDefaultMsgId = r.Byte()
+	s.Message = r.Bytes(int(r.Byte()))
+	s.TLVs = r.TLVs()
+	if payload, ok := s.TLVs[TagMessagePayload]; ok && len(s.Message) == 0 {
+		s.Message = payload
+	}
+	return r.Err()
+}
+
+func (s *ShortMessage) Encode() []byte {
+	w := &Writer{}
+	w.CString(s.ServiceType)
+	w.WriteByte(s.SourceTon)
+	w.WriteByte(s.SourceNpi)
+	w.CString(s.Source)
+	w.WriteByte(s.DestTon)
+	w.WriteByte(s.DestNpi)
+	w.CString(s.Destination)
+	w.WriteByte(s.EsmClass)
+	w.WriteByte(s.ProtocolId)
+	w.WriteByte(s.PriorityFlag)
+	w.CString(s.ScheduleDeliveryTime)
+	w.CString(s.ValidityPeriod)
+	w.WriteByte(s.RegisteredDelivery)
+	w.WriteByte(s.ReplaceIfPresent)
+	w.WriteByte(s.DataCoding)
+	w.WriteByte(s.SmDefaultMsgId)
+	if len(s.Message) > 254 {
+		w.WriteByte(0)
+		w.TLV(TagMessagePayload, s.Message)
+	} else {
+		w.WriteByte(byte(len(s.Message)))
+		w.Write(s.Message)
+	}
+	for tag, value := range s.TLVs {
+		if tag != TagMessagePayload {
+			w.TLV(tag, value)
+		}
+	}
+	return w.Bytes()
+}
+
+// QueryMessage is a body of query_sm
+type QueryMessage struct {
+	MessageId string
+	SourceTon byte
+	SourceNpi byte
+	Source    string
+}
+
+func (s *QueryMessage) Decode(body []byte) error {
+	r := NewReader(body)
+	s.MessageId = r.CString()
+	s.SourceTon = r.Byte()
+	s.SourceNpi = r.Byte()
+	s.Source = r.CString()
+	return r.Err()
+}
+
+func (s *QueryMessage) Encode() []byte {
+	w := &Writer{}
+	w.CString(s.MessageId)
+	w.WriteByte(s.SourceTon)
+	w.WriteByte(s.SourceNpi)
+	w.CString(s.Source)
+	return w.Bytes()
+}
+
+// QueryResult is a body of query_sm_resp
+type QueryResult struct {
+	MessageId    string
+	FinalDate    string
+	MessageState byte
+	ErrorCode    byte
+}
+
+func (s *QueryResult) Decode(body []byte) error {
+	r := NewReader(body)
+	s.MessageId = r.CString()
+	s.FinalDate = r.CString()
+	s.MessageState = r.Byte()
+	s.ErrorCode = r.Byte()
+	return r.Err()
+}
+
+func (s *QueryResult) Encode() []byte {
+	w := &Writer{}
+	w.CString(s.MessageId)
+	w.CString(s.FinalDate)
+	w.WriteByte(s.MessageState)
+	w.WriteByte(s.ErrorCode)
+	return w.Bytes()
+}
@@ -0,0 +1,407 @@
+package smpp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"io"
+	"log"
+	"net"
+	"smsgate-mock/data"
+	"smsgate-mock/smsc"
+	"smsgate-mock/utils"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SystemId is sent in bind responses
+const SystemId = "SMSGATEMOCK"
+
+// Dialect marks messages submitted via SMPP
+const Dialect = "smpp"
+
+const writeTimeout = 5 * time.Second
+
+// Server is SMPP 3.4 front-end of the message store
+type Server struct {
+	cfg      *utils.Settings
+	db       *bbolt.DB
+	smsc     *smsc.Center
+	mu       sync.Mutex
+	sessions map[*session]bool
+	listener net.Listener
+}
+
+func New(cfg *utils.Settings, db *bbolt.DB, center *smsc.Center) *Server {
+	srv := &Server{cfg: cfg, db: db, smsc: center, sessions: make(map[*session]bool)}
+	center.Subscribe(srv.deliverReport)
+	return srv
+}
+
+func (srv *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", srv.cfg.SmppPort))
+	if err != nil {
+		return err
+	}
+	return srv.Serve(l)
+}
+
+// Serve accepts connections until the listener is closed
+func (srv *Server) Serve(l net.Listener) error {
+	srv.mu.Lock()
+	srv.listener = l
+	srv.mu.Unlock()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		sess := &session{srv: srv, conn: conn}
+		srv.mu.Lock()
+		srv.sessions[sess] = true
+		srv.mu.Unlock()
+		go sess.serve()
+	}
+}
+
+// Close stops the listener and drops all sessions
+func (srv *Server) Close() error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for sess := range srv.sessions {
+		sess.conn.Close()
+	}
+	if srv.listener != nil {
+		return srv.listener.Close()
+	}
+	return nil
+}
+
+func (srv *Server) remove(sess *session) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	delete(srv.sessions, sess)
+}
+
+// receiver returns a session bound as receiver or transceiver for the sender
+func (srv *Server) receiver(senderUuid uuid.UUID) *session {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for sess := range srv.sessions {
+		if sess.canReceiveFor(senderUuid) {
+			return sess
+		}
+	}
+	return nil
+}
+
+// DeliverInbound sends inbound message to a receiver of its sender
+func (srv *Server) DeliverInbound(in *data.Inbound) {
+	sess := srv.receiver(in.SenderUuid)
+	if sess == nil {
+		return
+	}
+	coding, text := EncodeText(in.Text)
+	sm := &ShortMessage{Source: in.From, Destination: in.To, DataCoding: coding, Message: text}
+	if err := sess.send(&PDU{CommandId: DeliverSm, Sequence: sess.nextSequence(), Body: sm.Encode()}); err != nil {
+		log.Printf("Can't deliver inbound message %s via SMPP: %v", in.InboundUuid, err)
+	}
+}
+
+// deliverReport sends delivery receipt for messages submitted with registered delivery
+func (srv *Server) deliverReport(msg *data.Message) {
+	if msg.Dialect != Dialect || !data.IsFinalStatus(msg.Status) {
+		return
+	}
+	switch byte(msg.RegisteredDelivery) & 0x03 {
+	case 0:
+		return
+	case 2:
+		if msg.Status == data.StatusDelivered {
+			return
+		}
+	}
+	sess := srv.receiver(msg.SenderUuid)
+	if sess == nil {
+		log.Printf("No SMPP receiver for delivery receipt of %s", msg.MessageUuid)
+		return
+	}
+	state := messageState(msg.Status)
+	w := &Writer{}
+	w.CString(msg.MessageUuid.String())
+	sm := &ShortMessage{
+		Source:      msg.PhoneNumber,
+		Destination: msg.SenderName,
+		EsmClass:    EsmClassReceipt,
+		Message:     []byte(receipt(msg)),
+		TLVs: map[uint16][]byte{
+			TagReceiptedMessageId: w.Bytes(),
+			TagMessageState:       {state},
+		},
+	}
+	if err := sess.send(&PDU{CommandId: DeliverSm, Sequence: sess.nextSequence(), Body: sm.Encode()}); err != nil {
+		log.Printf("Can't send delivery receipt of %s via SMPP: %v", msg.MessageUuid, err)
+	}
+}
+
+// receipt makes the de-facto standard text of delivery receipt
+func receipt(msg *data.Message) string {
+	delivered := "000"
+	if msg.Status == data.StatusDelivered {
+		delivered = "001"
+	}
+	text := []rune(msg.MessageText)
+	if len(text) > 20 {
+		text = text[:20]
+	}
+	return fmt.Sprintf("id:%s sub:001 dlvrd:%s submit date:%s done date:%s stat:%s err:%03d text:%s",
+		msg.MessageUuid, delivered, msg.Create.UTC().Format("0601021504"), msg.Done.UTC().Format("0601021504"),
+		receiptStatus(msg.Status), msg.ErrorCode%1000, string(text))
+}
+
+func receiptStatus(status string) string {
+	switch status {
+	case data.StatusDelivered:
+		return "DELIVRD"
+	case data.StatusExpired:
+		return "EXPIRED"
+	case data.StatusUndelivered:
+		return "UNDELIV"
+	case data.StatusRejected:
+		return "REJECTD"
+	case data.StatusAccepted:
+		return "ACCEPTD"
+	case data.StatusScheduled, data.StatusQueued, data.StatusSent:
+		return "ENROUTE"
+	case data.StatusCanceled:
+		return "DELETED"
+	}
+	return "UNKNOWN"
+}
+
+func messageState(status string) byte {
+	switch status {
+	case data.StatusDelivered:
+		return StateDelivered
+	case data.StatusExpired:
+		return StateExpired
+	case data.StatusUndelivered:
+		return StateUndeliverable
+	case data.StatusRejected:
+		return StateRejected
+	case data.StatusAccepted:
+		return StateAccepted
+	case data.StatusScheduled, data.StatusQueued, data.StatusSent:
+		return StateEnroute
+	case data.StatusCanceled:
+		return StateDeleted
+	}
+	return StateUnknown
+}
+
+type session struct {
+	srv      *Server
+	conn     net.Conn
+	wmu      sync.Mutex
+	mu       sync.RWMutex
+	sender   *data.Sender
+	bindType uint32
+	sequence uint32
+}
+
+func (s *session) serve() {
+	defer s.srv.remove(s)
+	defer s.conn.Close()
+	for {
+		p, err := ReadPDU(s.conn)
+		if err != nil {
+			if err != io.EOF && !strings.Contains(err.Error(), "use of closed network connection") {
+				log.Printf("SMPP session %s: %v", s.conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if !s.handle(p) {
+			return
+		}
+	}
+}
+
+func (s *session) send(p *PDU) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := s.conn.Write(p.Bytes())
+	return err
+}
+
+func (s *session) nextSequence() uint32 {
+	return atomic.AddUint32(&s.sequence, 1)
+}
+
+func (s *session) bound() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sender != nil
+}
+
+func (s *session) canReceiveFor(senderUuid uuid.UUID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sender != nil && s.sender.SenderUuid == senderUuid &&
+		(s.bindType == BindReceiver || s.bindType == BindTransceiver)
+}
+
+func (s *session) canTransmit() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sender != nil && (s.bindType == BindTransmitter || s.bindType == BindTransceiver)
+}
+
+// handle processes one PDU, returns false when the session is over
+func (s *session) handle(p *PDU) bool {
+	var resp *PDU
+	switch p.CommandId {
+	case BindReceiver, BindTransmitter, BindTransceiver:
+		resp = s.bind(p)
+	case SubmitSm:
+		resp = s.submit(p)
+	case QuerySm:
+		resp = s.query(p)
+	case EnquireLink:
+		resp = p.Response(StatusOk, nil)
+	case Unbind:
+		s.send(p.Response(StatusOk, nil))
+		return false
+	case DeliverSmResp, EnquireLinkResp, GenericNack:
+		return true
+	default:
+		resp = &PDU{CommandId: GenericNack, Status: StatusInvCmdId, Sequence: p.Sequence}
+	}
+	if err := s.send(resp); err != nil {
+		log.Printf("SMPP session %s: can't send response: %v", s.conn.RemoteAddr(), err)
+		return false
+	}
+	return true
+}
+
+func (s *session) bind(p *PDU) *PDU {
+	w := &Writer{}
+	w.CString(SystemId)
+	if s.bound() {
+		return p.Response(StatusAlyBnd, w.Bytes())
+	}
+	req := &Bind{}
+	if err := req.Decode(p.Body); err != nil {
+		return p.Response(StatusInvMsgLen, w.Bytes())
+	}
+	sender := &data.Sender{}
+	if err := sender.LoadByLogin(s.srv.db, req.SystemId); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return p.Response(StatusInvSysId, w.Bytes())
+		}
+		log.Printf("Can't load sender %s for SMPP bind: %v", req.SystemId, err)
+		return p.Response(StatusBindFail, w.Bytes())
+	}
+	if sender.Password != req.Password {
+		return p.Response(StatusInvPaswd, w.Bytes())
+	}
+	s.mu.Lock()
+	s.sender = sender
+	s.bindType = p.CommandId
+	s.mu.Unlock()
+	return p.Response(StatusOk, w.Bytes())
+}
+
+func (s *session) submit(p *PDU) *PDU {
+	if !s.canTransmit() {
+		return p.Response(StatusInvBndSts, nil)
+	}
+	req := &ShortMessage{}
+	if err := req.Decode(p.Body); err != nil {
+		return p.Response(StatusInvMsgLen, nil)
+	}
+	if len(req.Destination) == 0 {
+		return p.Response(StatusInvDstAdr, nil)
+	}
+	// the sender is reloaded so that its balance and settings changed after bind are applied
+	sender := &data.Sender{}
+	if err := sender.LoadById(s.srv.db, s.sender.SenderUuid); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return p.Response(StatusInvSysId, nil)
+		}
+		log.Printf("Can't load sender %s for SMPP submit: %v", s.sender.SenderUuid, err)
+		return p.Response(StatusSysErr, nil)
+	}
+	now := time.Now()
+	msg := &data.Message{
+		Sender:             sender,
+		SenderName:         req.Source,
+		MessageType:        "TEXT",
+		MessageText:        DecodeText(req.DataCoding, req.Message),
+		PhoneNumber:        req.Destination,
+		Dialect:            Dialect,
+		RegisteredDelivery: int(req.RegisteredDelivery),
+	}
+	validity, err := ParseTime(req.ValidityPeriod, now)
+	if err != nil {
+		return p.Response(StatusSubmitFail, nil)
+	}
+	if !validity.IsZero() {
+		msg.ExpirationTimeout = int(validity.Sub(now) / time.Second)
+		if msg.ExpirationTimeout <= 0 {
+			msg.ExpirationTimeout = 1
+		}
+	}
+	if err := s.srv.smsc.Submit(msg); err != nil {
+		var tooLong *smsc.TooLongError
+		if errors.As(err, &tooLong) {
+			return p.Response(StatusInvMsgLen, nil)
+		}
+		var nameErr *smsc.SenderNameError
+		if errors.As(err, &nameErr) {
+			return p.Response(StatusInvSrcAdr, nil)
+		}
+		var balance *smsc.BalanceError
+		if errors.As(err, &balance) {
+			return p.Response(StatusSubmitFail, nil)
+		}
+		var limit *smsc.LimitError
+		if errors.As(err, &limit) {
+			if limit.Limit == smsc.LimitRate {
+				return p.Response(StatusThrottled, nil)
+			}
+			return p.Response(StatusMsgQFul, nil)
+		}
+		log.Printf("Can't save SMPP message: %v", err)
+		return p.Response(StatusSysErr, nil)
+	}
+	w := &Writer{}
+	w.CString(msg.MessageUuid.String())
+	return p.Response(StatusOk, w.Bytes())
+}
+
+func (s *session) query(p *PDU) *PDU {
+	if !s.canTransmit() {
+		return p.Response(StatusInvBndSts, nil)
+	}
+	req := &QueryMessage{}
+	if err := req.Decode(p.Body); err != nil {
+		return p.Response(StatusInvMsgLen, nil)
+	}
+	id, err := uuid.Parse(req.MessageId)
+	if err != nil {
+		return p.Response(StatusInvMsgId, nil)
+	}
+	msg := &data.Message{}
+	if err := msg.LoadById(s.srv.db, id); err != nil || msg.SenderUuid != s.sender.SenderUuid {
+		return p.Response(StatusInvMsgId, nil)
+	}
+	res := &QueryResult{MessageId: req.MessageId, MessageState: messageState(msg.Status), ErrorCode: byte(msg.ErrorCode)}
+	if !msg.Done.IsZero() {
+		res.FinalDate = FormatTime(msg.Done)
+	}
+	return p.Response(StatusOk, res.Encode())
+}
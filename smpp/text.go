@@ -0,0 +1,92 @@
+package smpp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+// DecodeText converts short message to a string, default and Latin-1 codings are read byte per character
+func DecodeText(coding byte, message []byte) string {
+	if coding == CodingUcs2 {
+		units := make([]uint16, len(message)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(message[i*2:])
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(message))
+	for i, b := range message {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// EncodeText chooses the default coding for ASCII text and UCS-2 for anything else
+func EncodeText(text string) (byte, []byte) {
+	ascii := true
+	for _, r := range text {
+		if r > 0x7F {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return CodingDefault, []byte(text)
+	}
+	units := utf16.Encode([]rune(text))
+	buf := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.BigEndian.PutUint16(buf[i*2:], u)
+	}
+	return CodingUcs2, buf
+}
+
+// FormatTime formats time as absolute SMPP time "YYMMDDhhmmsstnnp" in UTC
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("060102150405") + "000+"
+}
+
+// ParseTime parses absolute or relative SMPP time, empty string gives zero time
+func ParseTime(src string, now time.Time) (time.Time, error) {
+	if len(src) == 0 {
+		return time.Time{}, nil
+	}
+	if len(src) != 16 {
+		return time.Time{}, fmt.Errorf("bad time length %d", len(src))
+	}
+	if src[15] == 'R' {
+		var fields [6]int
+		for i := range fields {
+			v, err := strconv.Atoi(src[i*2 : i*2+2])
+			if err != nil {
+				return time.Time{}, fmt.Errorf("bad relative time %s: %v", src, err)
+			}
+			fields[i] = v
+		}
+		return now.AddDate(fields[0], fields[1], fields[2]).
+			Add(time.Duration(fields[3])*time.Hour + time.Duration(fields[4])*time.Minute + time.Duration(fields[5])*time.Second), nil
+	}
+	t, err := time.Parse("060102150405", src[:12])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad absolute time %s: %v", src, err)
+	}
+	t = t.Add(time.Duration(src[12]-'0') * 100 * time.Millisecond)
+	// offset in quarters of an hour from UTC
+	quarters, err := strconv.Atoi(src[13:15])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad time offset %s: %v", src, err)
+	}
+	offset := time.Duration(quarters) * 15 * time.Minute
+	switch src[15] {
+	case '+':
+		t = t.Add(-offset)
+	case '-':
+		t = t.Add(offset)
+	default:
+		return time.Time{}, fmt.Errorf("bad time direction %s", src)
+	}
+	return t, nil
+}
@@ -0,0 +1,45 @@
+package smsc
+
+import (
+	"fmt"
+	"log"
+	"smsgate-mock/data"
+)
+
+// BalanceError is returned by Submit when a prepaid sender can't pay for the message
+type BalanceError struct {
+	Balance float64
+	Price   float64
+}
+
+func (e *BalanceError) Error() string {
+	return fmt.Sprintf("insufficient balance %.5f for message price %.5f", e.Balance, e.Price)
+}
+
+// price returns the price of all segments of the message by the price table or the default price
+func (c *Center) price(msg *data.Message, digits string) (float64, error) {
+	price, err := (&data.Price{}).Find(c.db, digits, msg.Country)
+	if err != nil {
+		return 0, err
+	}
+	perSegment := c.cfg.DefaultPrice
+	if price != nil {
+		perSegment = price.PerSegment
+	}
+	return data.RoundMoney(perSegment * float64(msg.Segments)), nil
+}
+
+// refund returns prices of messages which failed or were canceled
+func (c *Center) refund(changed []*data.Message) {
+	for _, msg := range changed {
+		if msg.Price == 0 || !data.IsFinalStatus(msg.Status) || msg.Status == data.StatusDelivered {
+			continue
+		}
+		if !c.cfg.RefundFailed && msg.Status != data.StatusCanceled {
+			continue
+		}
+		if err := (&data.LedgerEntry{}).Refund(c.db, msg); err != nil {
+			log.Printf("Can't refund message %s: %v", msg.MessageUuid, err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package smsc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"smsgate-mock/data"
+	"strings"
+	"time"
+)
+
+// campaignTickLimit bounds messages one campaign submits per tick
+const campaignTickLimit = 1000
+
+// runCampaigns submits due messages of running campaigns
+func (c *Center) runCampaigns(now time.Time) {
+	c.campaignMu.Lock()
+	defer c.campaignMu.Unlock()
+	campaigns, err := (&data.Campaign{}).List(c.db, data.CampaignRunning)
+	if err != nil {
+		log.Printf("Can't list campaigns: %v", err)
+		return
+	}
+	for _, campaign := range campaigns {
+		if !campaign.NextAt.After(now) {
+			if err := c.advanceCampaign(campaign, now); err != nil {
+				log.Printf("Can't run campaign %s: %v", campaign.CampaignUuid, err)
+			}
+		}
+	}
+}
+
+// advanceCampaign submits the campaign's recipients due by now, rate limited recipients are retried later
+func (c *Center) advanceCampaign(campaign *data.Campaign, now time.Time) error {
+	sender := &data.Sender{}
+	if err := sender.LoadById(c.db, campaign.SenderUuid); err != nil {
+		if !strings.Contains(err.Error(), "not found") {
+			return err
+		}
+		// nobody can send the rest of the campaign
+		campaign.Status = data.CampaignAborted
+		campaign.LastError = err.Error()
+		campaign.Updated = now
+		campaign.Done = now
+		return campaign.Update(c.db)
+	}
+	recipients, err := campaign.LoadRecipients(c.db, campaign.Next, campaignTickLimit)
+	if err != nil {
+		return err
+	}
+	submitted := make(map[int]uuid.UUID)
+	for i := 0; i < len(recipients) && !campaign.NextAt.After(now); i++ {
+		recipient := &recipients[i]
+		msg := &data.Message{
+			Sender:       sender,
+			SenderName:   campaign.SenderName,
+			MessageType:  campaign.MessageType,
+			PhoneNumber:  recipient.PhoneNumber,
+			CampaignUuid: campaign.CampaignUuid,
+		}
+		text, err := campaign.Render(recipient)
+		if err == nil {
+			msg.MessageText = text
+			err = c.Submit(msg)
+		}
+		var limit *LimitError
+		if errors.As(err, &limit) {
+			campaign.NextAt = now.Add(limit.RetryAfter)
+			break
+		}
+		if err != nil {
+			campaign.Rejected += 1
+			campaign.LastError = fmt.Sprintf("%s: %v", recipient.PhoneNumber, err)
+		} else {
+			submitted[campaign.Next] = msg.MessageUuid
+		}
+		campaign.Next += 1
+		if campaign.Speed > 0 {
+			campaign.NextAt = campaign.NextAt.Add(time.Duration(float64(time.Second) / campaign.Speed))
+		}
+	}
+	campaign.Updated = now
+	if campaign.Next >= campaign.Total {
+		campaign.Status = data.CampaignCompleted
+		campaign.Done = now
+	}
+	return campaign.UpdateProgress(c.db, submitted)
+}
+
+// PauseCampaign stops a running campaign, messages already submitted go on
+func (c *Center) PauseCampaign(id uuid.UUID) (*data.Campaign, error) {
+	return c.changeCampaign(id, data.CampaignPaused, "paused", data.CampaignRunning)
+}
+
+// ResumeCampaign continues a paused campaign from the next recipient
+func (c *Center) ResumeCampaign(id uuid.UUID) (*data.Campaign, error) {
+	return c.changeCampaign(id, data.CampaignRunning, "resumed", data.CampaignPaused)
+}
+
+// AbortCampaign stops the campaign for good canceling its messages which haven't gone out yet
+func (c *Center) AbortCampaign(id uuid.UUID) (*data.Campaign, error) {
+	campaign, err := c.changeCampaign(id, data.CampaignAborted, "aborted", data.CampaignRunning, data.CampaignPaused)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := campaign.MessageUuids(c.db)
+	if err != nil {
+		return campaign, err
+	}
+	for _, msgId := range ids {
+		if _, err := c.Cancel(msgId); err != nil && !strings.Contains(err.Error(), "can't be canceled") &&
+			!strings.Contains(err.Error(), "not found") {
+			return campaign, err
+		}
+	}
+	return campaign, nil
+}
+
+// changeCampaign moves the campaign to the status if it's in one of the statuses from, action names the change in errors
+func (c *Center) changeCampaign(id uuid.UUID, status, action string, from ...string) (*data.Campaign, error) {
+	c.campaignMu.Lock()
+	defer c.campaignMu.Unlock()
+	campaign := &data.Campaign{}
+	if err := campaign.LoadById(c.db, id); err != nil {
+		return nil, err
+	}
+	allowed := false
+	for _, s := range from {
+		allowed = allowed || campaign.Status == s
+	}
+	if !allowed {
+		return nil, fmt.Errorf("campaign in status %s can't be %s", campaign.Status, action)
+	}
+	now := time.Now()
+	campaign.Status = status
+	campaign.Updated = now
+	switch status {
+	case data.CampaignRunning:
+		campaign.NextAt = now
+	case data.CampaignAborted:
+		campaign.Done = now
+	}
+	return campaign, campaign.Update(c.db)
+}
@@ -0,0 +1,64 @@
+package smsc
+
+import (
+	"math/rand"
+	"smsgate-mock/data"
+	"time"
+)
+
+// default error of messages failed by a carrier profile, GSM MAP system failure
+const (
+	carrierErrorCode    = 34
+	carrierErrorMessage = "system failure"
+)
+
+// route resolves the country and the carrier serving the number of the message, ported numbers are routed
+// to their new carrier
+func (c *Center) route(msg *data.Message) (*Lookup, error) {
+	lookup, err := c.Lookup(msg.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	msg.Country = lookup.Country
+	msg.Destination = lookup.PhoneNumber
+	if carrier := lookup.Serving(); carrier != nil {
+		msg.Mcc, msg.Mnc, msg.Carrier = carrier.Mcc, carrier.Mnc, carrier.Name
+	}
+	return lookup, nil
+}
+
+// applyCarrier fails the message if its carrier is down or by the carrier's failure rate unless a rule
+// has set the outcome, then it adds the carrier's latency to the delivery
+func (c *Center) applyCarrier(msg *data.Message, ruled bool, now time.Time) error {
+	if len(msg.Mcc) == 0 {
+		return nil
+	}
+	profile := &data.CarrierProfile{}
+	found, err := profile.Load(c.db, msg.Mcc+msg.Mnc)
+	if err != nil || !found {
+		return err
+	}
+	if !ruled {
+		name := ""
+		if profile.InOutage(now) {
+			name = "carrier: " + msg.Carrier + " outage"
+		} else if rand.Float64()*100 < profile.FailureRate {
+			name = "carrier: " + msg.Carrier + " failure"
+		}
+		if len(name) > 0 {
+			failure := &data.Rule{Name: name, Status: data.StatusUndelivered,
+				ErrorCode: profile.ErrorCode, ErrorMessage: profile.ErrorMessage}
+			if failure.ErrorCode == 0 {
+				failure.ErrorCode = carrierErrorCode
+			}
+			if len(failure.ErrorMessage) == 0 {
+				failure.ErrorMessage = carrierErrorMessage
+			}
+			failure.Apply(msg)
+		}
+	}
+	if len(msg.Plan) > 0 {
+		msg.Plan[len(msg.Plan)-1].Delay += profile.Latency
+	}
+	return nil
+}
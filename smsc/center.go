@@ -0,0 +1,242 @@
+package smsc
+
+import (
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"log"
+	"smsgate-mock/data"
+	"smsgate-mock/gsm"
+	"smsgate-mock/utils"
+	"sync"
+	"time"
+)
+
+// Center emulates SMS center behind the gate: it accepts messages and moves them through the delivery lifecycle
+type Center struct {
+	cfg       *utils.Settings
+	db        *bbolt.DB
+	lifecycle []data.LifecycleStep
+	mu        sync.RWMutex
+	listeners []func(msg *data.Message)
+	limiter   *limiter
+	// campaignMu serializes the campaign runner with status changes from the API
+	campaignMu sync.Mutex
+	stop       chan struct{}
+	stopped    chan struct{}
+}
+
+func New(cfg *utils.Settings, db *bbolt.DB) *Center {
+	lifecycle, err := data.ParseLifecycle(cfg.Lifecycle)
+	if err != nil {
+		log.Fatalf("Can't parse lifecycle %s: %v", cfg.Lifecycle, err)
+	}
+	return &Center{
+		cfg:       cfg,
+		db:        db,
+		lifecycle: lifecycle,
+		limiter:   newLimiter(),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+}
+
+// Subscribe registers a function called after every status change
+func (c *Center) Subscribe(fn func(msg *data.Message)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+// Submit saves a new message and plans its lifecycle, the outcome is set by the first matching rule.
+// It returns *TooLongError if the message has too many parts, *SenderNameError if the sender name isn't approved,
+// *BalanceError if a prepaid sender can't pay for it and *LimitError if the sender exceeds its rate limit or quota
+func (c *Center) Submit(msg *data.Message) error {
+	now := time.Now()
+	if err := c.plan(msg, now); err != nil {
+		return err
+	}
+	charged, err := msg.SaveCharged(c.db)
+	if err != nil {
+		c.release(msg, now)
+		return err
+	}
+	if !charged {
+		// the balance was spent by a concurrent message
+		c.release(msg, now)
+		return &BalanceError{Balance: msg.Sender.Balance, Price: msg.Price}
+	}
+	return nil
+}
+
+// SubmitBatch submits the messages like Submit saving the accepted ones with the batch in one transaction,
+// it returns the error of every message, nil for accepted ones
+func (c *Center) SubmitBatch(batch *data.Batch, msgs []*data.Message) ([]error, error) {
+	errs := make([]error, len(msgs))
+	planned := make([]*data.Message, 0, len(msgs))
+	now := time.Now()
+	for i, msg := range msgs {
+		if errs[i] = c.plan(msg, now); errs[i] == nil {
+			planned = append(planned, msg)
+		}
+	}
+	charged, err := batch.Save(c.db, planned)
+	if err != nil {
+		for _, msg := range planned {
+			c.release(msg, now)
+		}
+		return nil, err
+	}
+	j := 0
+	for i, msg := range msgs {
+		if errs[i] != nil {
+			continue
+		}
+		if !charged[j] {
+			c.release(msg, now)
+			errs[i] = &BalanceError{Balance: msg.Sender.Balance, Price: msg.Price}
+		}
+		j++
+	}
+	return errs, nil
+}
+
+// release returns the rate token and the quota reserved by plan for the message which wasn't accepted
+func (c *Center) release(msg *data.Message, now time.Time) {
+	c.limiter.give(msg.Sender)
+	if err := (&data.Usage{}).Release(c.db, msg.Sender, now); err != nil {
+		log.Printf("Can't release quota of sender %s: %v", msg.Sender.SenderUuid, err)
+	}
+}
+
+// plan runs the checks of a new message and plans its lifecycle
+func (c *Center) plan(msg *data.Message, now time.Time) error {
+	transliterate := msg.Transliterate
+	if transliterate == nil {
+		transliterate = msg.Sender.Transliterate
+	}
+	if transliterate != nil && *transliterate && !gsm.IsGSM7(msg.MessageText) {
+		_, original := gsm.Split(msg.MessageText)
+		msg.OriginalText = msg.MessageText
+		msg.OriginalSegments = len(original)
+		msg.MessageText = gsm.Transliterate(msg.MessageText)
+	}
+	msg.Encoding, msg.Parts = gsm.Split(msg.MessageText)
+	msg.Segments = len(msg.Parts)
+	if c.cfg.MaxParts > 0 && msg.Segments > c.cfg.MaxParts {
+		return &TooLongError{Segments: msg.Segments, MaxSegments: c.cfg.MaxParts}
+	}
+	lookup, err := c.route(msg)
+	if err != nil {
+		return err
+	}
+	if c.cfg.SenderNameRegistry {
+		if err := c.checkSenderName(msg); err != nil {
+			return err
+		}
+	}
+	price, err := c.price(msg, lookup.Digits())
+	if err != nil {
+		return err
+	}
+	msg.Price = price
+	if msg.Sender.IsPrepaid() && msg.Sender.Balance < msg.Price {
+		return &BalanceError{Balance: msg.Sender.Balance, Price: msg.Price}
+	}
+	msg.Plan = append([]data.LifecycleStep{}, c.lifecycle...)
+	rule, err := (&data.Rule{}).Match(c.db, msg, c.cfg.MagicNumbers)
+	if err != nil {
+		return err
+	}
+	if rule != nil {
+		rule.Apply(msg)
+	}
+	ruled := rule != nil
+	if c.cfg.SubscriberCheck {
+		ruled = c.checkSubscriber(msg, lookup, ruled) || ruled
+	}
+	if err := c.applyCarrier(msg, ruled, now); err != nil {
+		return err
+	}
+	// limits are taken last so that nothing fails after them
+	if ok, retryAfter := c.limiter.take(msg.Sender, now); !ok {
+		return &LimitError{Limit: LimitRate, RetryAfter: retryAfter}
+	}
+	exceeded, err := (&data.Usage{}).Reserve(c.db, msg.Sender, now)
+	if err != nil {
+		c.limiter.give(msg.Sender)
+		return err
+	}
+	if len(exceeded) > 0 {
+		c.limiter.give(msg.Sender)
+		return &LimitError{Limit: exceeded, RetryAfter: data.QuotaReset(exceeded, now).Sub(now)}
+	}
+	return nil
+}
+
+// Cancel cancels the message which hasn't gone out yet, it's refunded and reported like other status changes
+func (c *Center) Cancel(id uuid.UUID) (*data.Message, error) {
+	msg := &data.Message{}
+	if err := msg.Cancel(c.db, id, time.Now()); err != nil {
+		return nil, err
+	}
+	changed := []*data.Message{msg}
+	c.refund(changed)
+	c.notify(changed)
+	return msg, nil
+}
+
+// Quota returns the sender's usage and the number of messages it can send right now, -1 if unlimited
+func (c *Center) Quota(sender *data.Sender) (*data.Usage, float64, error) {
+	now := time.Now()
+	usage := &data.Usage{}
+	if err := usage.Load(c.db, sender.SenderUuid, now); err != nil {
+		return nil, 0, err
+	}
+	return usage, c.limiter.tokens(sender, now), nil
+}
+
+// Run processes due transitions until Stop is called
+func (c *Center) Run() {
+	interval := c.cfg.ProcessInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer close(c.stopped)
+	for {
+		select {
+		case now := <-ticker.C:
+			c.process(now)
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Stop stops Run and waits for it to return
+func (c *Center) Stop() {
+	close(c.stop)
+	<-c.stopped
+}
+
+func (c *Center) process(now time.Time) {
+	changed, err := (&data.Message{}).AdvanceDue(c.db, now)
+	if err != nil {
+		log.Printf("Can't process pending messages: %v", err)
+		return
+	}
+	c.refund(changed)
+	c.notify(changed)
+	c.runCampaigns(now)
+}
+
+func (c *Center) notify(changed []*data.Message) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, msg := range changed {
+		for _, fn := range c.listeners {
+			fn(msg)
+		}
+	}
+}
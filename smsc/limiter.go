@@ -0,0 +1,113 @@
+package smsc
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"math"
+	"smsgate-mock/data"
+	"sync"
+	"time"
+)
+
+// limits
+const (
+	LimitRate    = "rate"
+	LimitDaily   = data.QuotaDaily
+	LimitMonthly = data.QuotaMonthly
+)
+
+// LimitError is returned by Submit when the sender exceeds its rate limit or quota
+type LimitError struct {
+	Limit      string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == LimitRate {
+		return fmt.Sprintf("rate limit exceeded, retry after %v", e.RetryAfter)
+	}
+	return fmt.Sprintf("%s quota exceeded, retry after %v", e.Limit, e.RetryAfter)
+}
+
+// TooLongError is returned by Submit when the message has more parts than MAX_PARTS
+type TooLongError struct {
+	Segments    int
+	MaxSegments int
+}
+
+func (e *TooLongError) Error() string {
+	return fmt.Sprintf("message is too long: %d parts, maximum is %d", e.Segments, e.MaxSegments)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter keeps a token bucket per sender
+type limiter struct {
+	mu      sync.Mutex
+	buckets map[uuid.UUID]*tokenBucket
+}
+
+func newLimiter() *limiter {
+	return &limiter{buckets: make(map[uuid.UUID]*tokenBucket)}
+}
+
+func burst(sender *data.Sender) float64 {
+	if sender.RateBurst > 0 {
+		return float64(sender.RateBurst)
+	}
+	return math.Max(1, math.Ceil(sender.RateLimit))
+}
+
+// refill returns the sender's bucket with tokens added since the last call, mu must be locked
+func (l *limiter) refill(sender *data.Sender, now time.Time) *tokenBucket {
+	b, ok := l.buckets[sender.SenderUuid]
+	if !ok {
+		b = &tokenBucket{tokens: burst(sender), last: now}
+		l.buckets[sender.SenderUuid] = b
+	}
+	if now.After(b.last) {
+		b.tokens = math.Min(burst(sender), b.tokens+now.Sub(b.last).Seconds()*sender.RateLimit)
+		b.last = now
+	}
+	return b
+}
+
+// take removes a token from the sender's bucket or returns time till the next one
+func (l *limiter) take(sender *data.Sender, now time.Time) (bool, time.Duration) {
+	if sender.RateLimit <= 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.refill(sender, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / sender.RateLimit * float64(time.Second))
+}
+
+// give returns a token taken for a message which wasn't accepted
+func (l *limiter) give(sender *data.Sender) {
+	if sender.RateLimit <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[sender.SenderUuid]; ok {
+		b.tokens = math.Min(burst(sender), b.tokens+1)
+	}
+}
+
+// tokens returns the number of messages the sender can send right now, -1 if unlimited
+func (l *limiter) tokens(sender *data.Sender, now time.Time) float64 {
+	if sender.RateLimit <= 0 {
+		return -1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.refill(sender, now).tokens
+}
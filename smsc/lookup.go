@@ -0,0 +1,109 @@
+package smsc
+
+import (
+	"smsgate-mock/data"
+	"smsgate-mock/phone"
+)
+
+// errors of messages failed by the subscriber check, GSM MAP codes like the magic numbers
+const (
+	unknownSubscriberCode    = 1
+	unknownSubscriberMessage = "unknown subscriber"
+	absentSubscriberCode     = 27
+	absentSubscriberMessage  = "absent subscriber"
+)
+
+// Lookup is an HLR response for a number
+type Lookup struct {
+	// PhoneNumber is in E.164 format, empty if the number can't be normalized
+	PhoneNumber string
+	Valid       bool
+	// Reason why the number isn't valid
+	Reason   string
+	Country  string
+	LineType string
+	// Carrier is the one the number range was allocated to, Ported is the one serving it now if it differs
+	Carrier *phone.Carrier
+	Ported  *phone.Carrier
+	Roaming string
+	Status  string
+	// Reachable is true for valid active subscribers
+	Reachable bool
+	// Known is true if the number is in the subscriber table, other valid numbers are active subscribers
+	// with a mobile line if their carrier is known
+	Known bool
+}
+
+// Serving is the carrier serving the number, nil if it's unknown
+func (l *Lookup) Serving() *phone.Carrier {
+	if l.Ported != nil {
+		return l.Ported
+	}
+	return l.Carrier
+}
+
+// Digits are international digits of the number without +
+func (l *Lookup) Digits() string {
+	if len(l.PhoneNumber) == 0 {
+		return ""
+	}
+	return l.PhoneNumber[1:]
+}
+
+// Lookup resolves the number in the numbering plans, the carrier table and the subscriber table,
+// numbers without a country code are read in the default country
+func (c *Center) Lookup(number string) (*Lookup, error) {
+	ret := &Lookup{Status: data.SubscriberUnknown}
+	e164, err := phone.Normalize(number, c.cfg.DefaultCountry)
+	if err != nil {
+		ret.Reason = err.Error()
+		return ret, nil
+	}
+	ret.PhoneNumber = e164
+	ret.Carrier = phone.LookupCarrier(ret.Digits())
+	if ret.Carrier != nil {
+		ret.Country = ret.Carrier.Country
+	} else if plan := phone.PlanByCode(ret.Digits()); plan != nil {
+		ret.Country = plan.Country
+	}
+	subscriber := &data.Subscriber{}
+	ret.Known, err = subscriber.Load(c.db, e164)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Known {
+		ret.LineType = subscriber.LineType
+		ret.Status = subscriber.Status
+		ret.Roaming = subscriber.Roaming
+		if ported := phone.CarrierById(subscriber.PortedTo); ported != nil && (ret.Carrier == nil || ported.Id() != ret.Carrier.Id()) {
+			ret.Ported = ported
+		}
+	} else {
+		ret.Status = data.SubscriberActive
+		if ret.Carrier != nil {
+			ret.LineType = data.LineMobile
+		}
+	}
+	ret.Valid = ret.Status != data.SubscriberUnknown
+	if !ret.Valid {
+		ret.Reason = "number isn't allocated"
+	}
+	ret.Reachable = ret.Status == data.SubscriberActive
+	return ret, nil
+}
+
+// checkSubscriber fails the message to an invalid or absent subscriber unless a rule has set the outcome,
+// it returns true if the message is failed
+func (c *Center) checkSubscriber(msg *data.Message, lookup *Lookup, ruled bool) bool {
+	if ruled || lookup.Reachable {
+		return false
+	}
+	failure := &data.Rule{Name: "subscriber: unknown", Status: data.StatusUndelivered,
+		ErrorCode: unknownSubscriberCode, ErrorMessage: unknownSubscriberMessage}
+	if lookup.Status == data.SubscriberAbsent {
+		failure = &data.Rule{Name: "subscriber: absent", Status: data.StatusUndelivered,
+			ErrorCode: absentSubscriberCode, ErrorMessage: absentSubscriberMessage}
+	}
+	failure.Apply(msg)
+	return true
+}
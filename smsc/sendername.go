@@ -0,0 +1,57 @@
+package smsc
+
+import (
+	"fmt"
+	"smsgate-mock/data"
+)
+
+// reasons of sender name rejection
+const (
+	NameNotRegistered = "not registered"
+	NameNotApproved   = "not approved"
+	NameNotAllowed    = "not allowed"
+)
+
+// SenderNameError is returned by Submit when the registry is enabled and the sender name can't be used
+type SenderNameError struct {
+	Name   string
+	Reason string
+	// Status of a registered name, Country of the destination
+	Status  string
+	Country string
+}
+
+func (e *SenderNameError) Error() string {
+	switch e.Reason {
+	case NameNotApproved:
+		return fmt.Sprintf("sender name %s is %s", e.Name, e.Status)
+	case NameNotAllowed:
+		if e.Country == "" {
+			return fmt.Sprintf("sender name %s is not allowed for the destination", e.Name)
+		}
+		return fmt.Sprintf("sender name %s is not allowed in %s", e.Name, e.Country)
+	}
+	return fmt.Sprintf("sender name %s is not registered", e.Name)
+}
+
+// checkSenderName looks the name up in the registry and checks the destination country
+func (c *Center) checkSenderName(msg *data.Message) error {
+	name := &data.SenderName{}
+	found, err := name.Find(c.db, msg.Sender.SenderUuid, msg.SenderName)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &SenderNameError{Name: msg.SenderName, Reason: NameNotRegistered}
+	}
+	if name.Status != data.SenderNameApproved {
+		return &SenderNameError{Name: msg.SenderName, Reason: NameNotApproved, Status: name.Status}
+	}
+	if len(name.Countries) == 0 {
+		return nil
+	}
+	if !name.AllowedIn(msg.Country) {
+		return &SenderNameError{Name: msg.SenderName, Reason: NameNotAllowed, Country: msg.Country}
+	}
+	return nil
+}
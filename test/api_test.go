@@ -2,38 +2,149 @@ package api_test
 
 import (
 	"bytes"
+	"io/ioutil"
 	"encoding/json"
 	"fmt"
+	"github.com/google/uuid"
 	"go.etcd.io/bbolt"
 	"gopkg.in/go-playground/assert.v1"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"smsgate-mock/api"
 	"smsgate-mock/data"
 	"smsgate-mock/utils"
+	"smsgate-mock/webhook"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+)
+
+var (
+	dbMu sync.Mutex
+	dbs  = make(map[*testing.T]*bbolt.DB)
 )
 
 func initApi(t *testing.T) *api.App {
-	cfg := utils.ReadSettings()
-	db, err := bbolt.Open(cfg.DbPath, 0600, nil)
+	return initApiWithSettings(t, utils.ReadSettings())
+}
+
+// initApiWithSettings starts the app on a temporary database of the test, so tests don't see each other's data
+// and can be run again; apps started by one test share the database
+func initApiWithSettings(t *testing.T, cfg *utils.Settings) *api.App {
+	return api.Init(cfg, testDb(t))
+}
+
+func testDb(t *testing.T) *bbolt.DB {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	if db, ok := dbs[t]; ok {
+		return db
+	}
+	dir, err := ioutil.TempDir("", "smsgate-mock")
 	if err != nil {
-		log.Fatalf("Can't open database %s: %v", cfg.DbPath, err)
+		log.Fatalf("Can't create database directory: %v", err)
+	}
+	db, err := bbolt.Open(filepath.Join(dir, "test.db"), 0600, nil)
+	if err != nil {
+		log.Fatalf("Can't open database in %s: %v", dir, err)
 	}
 	data.InitBuckets(db)
-	app := api.Init(cfg, db)
-	return app
+	dbs[t] = db
+	t.Cleanup(func() {
+		dbMu.Lock()
+		delete(dbs, t)
+		dbMu.Unlock()
+		db.Close()
+		os.RemoveAll(dir)
+	})
+	return db
+}
+
+func addSender(t *testing.T, app *api.App, login, password string) api.SenderOut {
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(&api.SenderIn{Login: login, Password: password})
+	req, _ := http.NewRequest("POST", "/api/v1/sender", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	res := api.SenderOut{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res
+}
+
+// deleteEntity deletes the entity at the API path and checks that it answers 204 No Content
+func deleteEntity(t *testing.T, app *api.App, path string) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", path, nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
+
+func findSender(t *testing.T, app *api.App, id uuid.UUID) api.SenderOut {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/sender", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var list []api.SenderOut
+	json.Unmarshal(w.Body.Bytes(), &list)
+	for _, s := range list {
+		if s.SenderUuid == id {
+			return s
+		}
+	}
+	t.Fatalf("sender %s not found", id)
+	return api.SenderOut{}
+}
+
+func sendMessage(t *testing.T, app *api.App, msg *api.MessageIn) api.MessageOut {
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(msg)
+	req, _ := http.NewRequest("POST", "/api/v1/message", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	res := api.MessageOut{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res
+}
+
+func waitStatus(t *testing.T, app *api.App, id uuid.UUID, status string) api.MessageStatusOut {
+	res := api.MessageStatusOut{}
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/message/"+id.String(), nil)
+		app.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &res)
+		if res.Status == status {
+			return res
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("message %s has status %s instead of %s", id, res.Status, status)
+	return res
 }
 
 func TestMock(t *testing.T) {
 	app := initApi(t)
+	defer app.Close()
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/api/v1/sender", bytes.NewBuffer([]byte(`{"login": "test", "password":"123"}`)))
 	app.ServeHTTP(w, req)
 	assert.Equal(t, 201, w.Code)
-	res := api.SenderOut{}
+	res := api.SenderCreatedOut{}
 	json.Unmarshal(w.Body.Bytes(), &res)
+	// the webhook secret is shown on creation only
+	assert.NotEqual(t, "", res.Secret)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/sender", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, false, strings.Contains(w.Body.String(), res.Secret))
 
 	w = httptest.NewRecorder()
 	edit := &api.SenderEditIn{SenderUuid: res.SenderUuid, Password: "newpwd"}
@@ -49,10 +160,23 @@ func TestMock(t *testing.T) {
 	app.ServeHTTP(w, req)
 	assert.Equal(t, 204, w.Code)
 
+	callbackUrl := "http://localhost/dlr"
 	w = httptest.NewRecorder()
-	req, _ = http.NewRequest("GET", "/api/v1/sender", nil)
+	edit = &api.SenderEditIn{SenderUuid: res.SenderUuid, CallbackUrl: &callbackUrl}
+	data, _ = json.Marshal(edit)
+	req, _ = http.NewRequest("PATCH", "/api/v1/sender/" + res.SenderUuid.String(), bytes.NewBuffer(data))
 	app.ServeHTTP(w, req)
-	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, 204, w.Code)
+	assert.Equal(t, callbackUrl, findSender(t, app, res.SenderUuid).CallbackUrl)
+
+	// an explicit empty url removes the callback
+	callbackUrl = ""
+	w = httptest.NewRecorder()
+	data, _ = json.Marshal(edit)
+	req, _ = http.NewRequest("PATCH", "/api/v1/sender/" + res.SenderUuid.String(), bytes.NewBuffer(data))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+	assert.Equal(t, "", findSender(t, app, res.SenderUuid).CallbackUrl)
 
 	w = httptest.NewRecorder()
 	req, _ = http.NewRequest("POST", "/api/v1/sender/check_connection/" + res.SenderUuid.String(), bytes.NewBuffer([]byte(`{"login": "new_login", "password":"newpwd"}`)))
@@ -129,3 +253,255 @@ func TestMock(t *testing.T) {
 	app.ServeHTTP(w, req)
 	assert.Equal(t, 204, w.Code)
 }
+
+func TestLifecycle(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:50ms,SENT:50ms,DELIVERED:50ms"
+	cfg.ProcessInterval = 10 * time.Millisecond
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	sender := addSender(t, app, "lifecycle", "123")
+	msg := sendMessage(t, app, &api.MessageIn{
+		Login:       "lifecycle",
+		Password:    "123",
+		SenderName:  "TEST",
+		MessageType: "TEXT",
+		MessageText: "Lifecycle",
+		PhoneNumber: "81234567892",
+	})
+	assert.Equal(t, "QUEUED", msg.Status)
+
+	status := waitStatus(t, app, msg.MessageUuid, "DELIVERED")
+	assert.Equal(t, false, status.Sent.IsZero())
+	assert.Equal(t, false, status.Done.Before(status.Sent))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/message/"+msg.MessageUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
+
+func TestExpiration(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,SENT:10ms,DELIVERED:1m"
+	cfg.ProcessInterval = 10 * time.Millisecond
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	sender := addSender(t, app, "expiration", "123")
+	msg := sendMessage(t, app, &api.MessageIn{
+		Login:             "expiration",
+		Password:          "123",
+		SenderName:        "TEST",
+		MessageType:       "TEXT",
+		MessageText:       "Expiration",
+		ExpirationTimeout: 1,
+		PhoneNumber:       "81234567893",
+	})
+	waitStatus(t, app, msg.MessageUuid, "SENT")
+	time.Sleep(time.Second)
+	status := waitStatus(t, app, msg.MessageUuid, "EXPIRED")
+	assert.Equal(t, status.Expires, status.Done)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
+
+func TestDeliveryReport(t *testing.T) {
+	reports := make(chan api.DeliveryReportOut, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := api.DeliveryReportOut{}
+		json.NewDecoder(r.Body).Decode(&report)
+		reports <- report
+	}))
+	defer srv.Close()
+
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,DELIVERED:100ms"
+	cfg.ProcessInterval = 10 * time.Millisecond
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	sender := addSender(t, app, "callback", "123")
+	msg := sendMessage(t, app, &api.MessageIn{
+		Login:       "callback",
+		Password:    "123",
+		SenderName:  "TEST",
+		MessageType: "TEXT",
+		MessageText: "Callback",
+		PhoneNumber: "81234567894",
+		CallbackUrl: srv.URL,
+	})
+	for _, status := range []string{"ACCEPTED", "DELIVERED"} {
+		select {
+		case report := <-reports:
+			assert.Equal(t, msg.MessageUuid, report.MessageUuid)
+			assert.Equal(t, status, report.Status)
+			assert.Equal(t, "81234567894", report.PhoneNumber)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no delivery report with status %s", status)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
+
+func TestWebhookRetries(t *testing.T) {
+	var failing int32 = 1
+	requests := make(chan *http.Request, 10)
+	bodies := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- r
+		bodies <- string(body)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "DELIVERED:10ms"
+	cfg.ProcessInterval = 10 * time.Millisecond
+	cfg.WebhookMaxAttempts = 2
+	cfg.WebhookBackoff = 10 * time.Millisecond
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(&api.SenderIn{Login: "webhook", Password: "123", CallbackUrl: srv.URL, Secret: "secret"})
+	req, _ := http.NewRequest("POST", "/api/v1/sender", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	sender := api.SenderOut{}
+	json.Unmarshal(w.Body.Bytes(), &sender)
+	msg := sendMessage(t, app, &api.MessageIn{
+		Login:       "webhook",
+		Password:    "123",
+		SenderName:  "TEST",
+		MessageType: "TEXT",
+		MessageText: "Webhook",
+		PhoneNumber: "81234567895",
+	})
+
+	var dead *api.WebhookOut
+	for i := 0; i < 100 && dead == nil; i++ {
+		time.Sleep(20 * time.Millisecond)
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/v1/webhook/dead?limit=100", nil)
+		app.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		var hooks []*api.WebhookOut
+		json.Unmarshal(w.Body.Bytes(), &hooks)
+		for _, hook := range hooks {
+			if hook.SenderUuid == sender.SenderUuid {
+				dead = hook
+			}
+		}
+	}
+	if dead == nil {
+		t.Fatalf("webhook didn't get to dead letters")
+	}
+	assert.Equal(t, 2, dead.Attempts)
+	assert.Equal(t, "unexpected status 500", dead.LastError)
+	for i := 1; i <= 2; i++ {
+		r := <-requests
+		<-bodies
+		assert.Equal(t, strconv.Itoa(i), r.Header.Get(webhook.HeaderAttempt))
+		assert.Equal(t, dead.WebhookUuid.String(), r.Header.Get(webhook.HeaderId))
+	}
+
+	atomic.StoreInt32(&failing, 0)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/webhook/dead/"+dead.WebhookUuid.String()+"/replay", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	select {
+	case r := <-requests:
+		body := <-bodies
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		assert.Equal(t, webhook.Sign("secret", timestamp, body), r.Header.Get(webhook.HeaderSignature))
+		report := api.DeliveryReportOut{}
+		json.Unmarshal([]byte(body), &report)
+		assert.Equal(t, msg.MessageUuid, report.MessageUuid)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("replayed webhook wasn't sent")
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
+
+func TestInbound(t *testing.T) {
+	forwarded := make(chan api.InboundOut, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := api.InboundOut{}
+		json.NewDecoder(r.Body).Decode(&in)
+		forwarded <- in
+	}))
+	defer srv.Close()
+
+	cfg := utils.ReadSettings()
+	cfg.ProcessInterval = 10 * time.Millisecond
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(&api.SenderIn{Login: "inbound", Password: "123", MoCallbackUrl: srv.URL})
+	req, _ := http.NewRequest("POST", "/api/v1/sender", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	sender := api.SenderOut{}
+	json.Unmarshal(w.Body.Bytes(), &sender)
+	sendMessage(t, app, &api.MessageIn{
+		Login:       "inbound",
+		Password:    "123",
+		SenderName:  "SHOP",
+		MessageType: "TEXT",
+		MessageText: "Reply YES to confirm",
+		PhoneNumber: "81234567896",
+	})
+
+	w = httptest.NewRecorder()
+	body, _ = json.Marshal(&api.InboundIn{From: "81234567896", To: "SHOP", Text: "YES"})
+	req, _ = http.NewRequest("POST", "/api/v1/inbound", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	in := api.InboundOut{}
+	json.Unmarshal(w.Body.Bytes(), &in)
+	assert.Equal(t, sender.SenderUuid, in.SenderUuid)
+
+	select {
+	case mo := <-forwarded:
+		assert.Equal(t, in.InboundUuid, mo.InboundUuid)
+		assert.Equal(t, "YES", mo.Text)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("inbound message wasn't forwarded")
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/inbound?phoneNumber=81234567896", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var list []*api.InboundOut
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Equal(t, 1, len(list))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/inbound/"+in.InboundUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
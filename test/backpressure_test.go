@@ -0,0 +1,66 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setBackpressure(t *testing.T, app *api.App, limits *api.BackpressureIn) api.BackpressureOut {
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(limits)
+	req, _ := http.NewRequest("PUT", "/api/v1/admin/backpressure", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	res := api.BackpressureOut{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res
+}
+
+func TestBackpressure(t *testing.T) {
+	app := initApi(t)
+	defer app.Close()
+	slow := addFault(t, app, &api.FaultIn{Name: "slow", Route: "/api/v1/sender", Method: "GET", Latency: "300ms"})
+	setBackpressure(t, app, &api.BackpressureIn{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: "2s", RetryAfter: "3s"})
+
+	codes := make([]int, 3)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/sender", nil)
+			app.ServeHTTP(w, req)
+			codes[i] = w.Code
+			if w.Code == 503 {
+				assert.Equal(t, "3", w.Header().Get("Retry-After"))
+			}
+		}(i)
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/admin/backpressure", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	stats := api.BackpressureOut{}
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	assert.Equal(t, 1, stats.InFlight)
+	assert.Equal(t, 1, stats.Queued)
+	assert.Equal(t, uint64(1), stats.Rejected)
+
+	wg.Wait()
+	assert.Equal(t, []int{200, 200, 503}, codes)
+
+	stats = setBackpressure(t, app, &api.BackpressureIn{MaxInFlight: 0})
+	assert.Equal(t, 0, stats.InFlight)
+	assert.Equal(t, uint64(2), stats.Served)
+	deleteFault(t, app, slow)
+}
@@ -0,0 +1,81 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,DELIVERED:10ms"
+	cfg.MaxParts = 2
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	addSender(t, app, "batch", "123")
+	sendBatch := func(batch *api.BatchIn) *httptest.ResponseRecorder {
+		batch.Login = "batch"
+		batch.Password = "123"
+		batch.SenderName = "BATCH"
+		batch.MessageType = "TEXT"
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(batch)
+		req, _ := http.NewRequest("POST", "/api/v1/batch", bytes.NewBuffer(body))
+		app.ServeHTTP(w, req)
+		return w
+	}
+	batchStatus := func(res api.BatchOut) api.BatchStatusOut {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/batch/"+res.BatchUuid.String(), nil)
+		app.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		status := api.BatchStatusOut{}
+		json.Unmarshal(w.Body.Bytes(), &status)
+		return status
+	}
+
+	assert.Equal(t, 422, sendBatch(&api.BatchIn{MessageText: "hello"}).Code)
+	assert.Equal(t, 422, sendBatch(&api.BatchIn{MessageText: "hello", PhoneNumbers: []string{"81234567818"},
+		Messages: []api.BatchMessageIn{{PhoneNumber: "81234567819"}}}).Code)
+
+	w := sendBatch(&api.BatchIn{MessageText: "reminder", PhoneNumbers: []string{"81234567818", "81234567819", "81234567820"}})
+	assert.Equal(t, 201, w.Code)
+	res := api.BatchOut{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	assert.Equal(t, 3, res.Accepted)
+	assert.Equal(t, 0, res.Rejected)
+	assert.Equal(t, "81234567819", res.Results[1].PhoneNumber)
+	msg := waitStatus(t, app, *res.Results[2].MessageUuid, "DELIVERED")
+	assert.Equal(t, res.BatchUuid, *msg.BatchUuid)
+	status := batchStatus(res)
+	for i := 0; i < 50 && status.Statuses["DELIVERED"] != 3; i++ {
+		time.Sleep(20 * time.Millisecond)
+		status = batchStatus(res)
+	}
+	assert.Equal(t, map[string]int{"DELIVERED": 3}, status.Statuses)
+
+	// individual messages are rejected one by one
+	w = sendBatch(&api.BatchIn{MessageText: "reminder", Messages: []api.BatchMessageIn{
+		{PhoneNumber: "81234567821", MessageText: strings.Repeat("a", 500)},
+		{PhoneNumber: "81234567821"},
+	}})
+	assert.Equal(t, 201, w.Code)
+	res = api.BatchOut{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	assert.Equal(t, 1, res.Accepted)
+	assert.Equal(t, 1, res.Rejected)
+	assert.Equal(t, false, res.Results[0].Accepted)
+	assert.Equal(t, api.CodeMessageTooLong, res.Results[0].Code)
+	assert.Equal(t, true, res.Results[1].Accepted)
+	assert.Equal(t, "QUEUED", res.Results[1].Status)
+	status = batchStatus(res)
+	assert.Equal(t, 2, status.Total)
+	assert.Equal(t, 1, status.Rejected)
+}
@@ -0,0 +1,128 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBilling(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,DELIVERED:10ms"
+	cfg.DefaultCountry = "RU"
+	cfg.MagicNumbers = true
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	prepaid := true
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(&api.SenderIn{Login: "billing", Password: "123", Prepaid: &prepaid})
+	req, _ := http.NewRequest("POST", "/api/v1/sender", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	sender := api.SenderOut{}
+	json.Unmarshal(w.Body.Bytes(), &sender)
+	account := "/api/v1/account/" + sender.SenderUuid.String()
+
+	prices := make([]api.PriceOut, 0)
+	for _, in := range []api.PriceIn{{Prefix: "+71234567816", PerSegment: 0.05}, {Prefix: "7123456000", PerSegment: 0.04}} {
+		w = httptest.NewRecorder()
+		body, _ = json.Marshal(&in)
+		req, _ = http.NewRequest("POST", "/api/v1/price", bytes.NewBuffer(body))
+		app.ServeHTTP(w, req)
+		assert.Equal(t, 201, w.Code)
+		price := api.PriceOut{}
+		json.Unmarshal(w.Body.Bytes(), &price)
+		prices = append(prices, price)
+	}
+	send := func(text, phone string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(&api.MessageIn{
+			Login:       "billing",
+			Password:    "123",
+			SenderName:  "BILLING",
+			MessageType: "TEXT",
+			MessageText: text,
+			PhoneNumber: phone,
+		})
+		req, _ := http.NewRequest("POST", "/api/v1/message", bytes.NewBuffer(body))
+		app.ServeHTTP(w, req)
+		return w
+	}
+	topUp := func(amount float64) {
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(&api.TopUpIn{Amount: amount, Description: "test"})
+		req, _ := http.NewRequest("POST", account+"/topup", bytes.NewBuffer(body))
+		app.ServeHTTP(w, req)
+		assert.Equal(t, 201, w.Code)
+	}
+	balance := func() float64 {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", account, nil)
+		app.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		res := api.AccountOut{}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return res.Balance
+	}
+
+	w = send("hello", "81234567816")
+	assert.Equal(t, 402, w.Code)
+	res := api.BalanceErrorMessage{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	assert.Equal(t, api.CodeInsufficientBalance, res.Code)
+	assert.Equal(t, 0.05, res.Price)
+
+	topUp(0.12)
+	w = send(strings.Repeat("a", 161), "81234567816")
+	assert.Equal(t, 201, w.Code)
+	msg := api.MessageOut{}
+	json.Unmarshal(w.Body.Bytes(), &msg)
+	assert.Equal(t, 0.1, msg.Price)
+	assert.Equal(t, 0.02, balance())
+	w = send("hello", "81234567816")
+	assert.Equal(t, 402, w.Code)
+
+	// failed messages are refunded
+	topUp(0.1)
+	w = send("hello", "81234560001")
+	assert.Equal(t, 201, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &msg)
+	assert.Equal(t, 0.04, msg.Price)
+	waitStatus(t, app, msg.MessageUuid, "UNDELIVERED")
+	// the refund follows the status change
+	for i := 0; i < 50 && balance() != 0.12; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.Equal(t, 0.12, balance())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", account+"/ledger", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	ledger := make([]api.LedgerEntryOut, 0)
+	json.Unmarshal(w.Body.Bytes(), &ledger)
+	types := make([]string, 0)
+	for _, entry := range ledger {
+		types = append(types, entry.Type)
+	}
+	assert.Equal(t, []string{"TOPUP", "DEBIT", "TOPUP", "DEBIT", "REFUND"}, types)
+	assert.Equal(t, 0.12, ledger[4].Balance)
+
+	for _, price := range prices {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("DELETE", "/api/v1/price/"+price.PriceUuid.String(), nil)
+		app.ServeHTTP(w, req)
+		assert.Equal(t, 204, w.Code)
+	}
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
@@ -0,0 +1,121 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCampaigns(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,DELIVERED:10ms"
+	cfg.ProcessInterval = 20 * time.Millisecond
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	addSender(t, app, "campaign", "123")
+	call := func(method, url string, body interface{}) (int, api.CampaignOut) {
+		w := httptest.NewRecorder()
+		bindata, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, url, bytes.NewBuffer(bindata))
+		app.ServeHTTP(w, req)
+		res := api.CampaignOut{}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res
+	}
+	waitCampaign := func(id string, done func(campaign api.CampaignOut) bool) api.CampaignOut {
+		var campaign api.CampaignOut
+		for i := 0; i < 150; i++ {
+			_, campaign = call("GET", "/api/v1/campaign/"+id, nil)
+			if done(campaign) {
+				return campaign
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("campaign %s is stuck: %+v", id, campaign.Progress)
+		return campaign
+	}
+	in := &api.CampaignIn{
+		Login:       "campaign",
+		Password:    "123",
+		Name:        "spring sale",
+		SenderName:  "CAMPAIGN",
+		MessageType: "TEXT",
+		Template:    "Hi {{.name}}, spring sale starts today",
+		Speed:       5,
+		Recipients: []api.CampaignRecipientIn{
+			{PhoneNumber: "81234567822", Values: map[string]string{"name": "Ann"}},
+			{PhoneNumber: "81234567823", Values: map[string]string{"name": "Bob"}},
+			{PhoneNumber: "81234567824", Values: map[string]string{"name": "Eve"}},
+			{PhoneNumber: "81234567825"},
+		},
+	}
+
+	code, _ := call("POST", "/api/v1/campaign", &api.CampaignIn{Login: "campaign", Password: "123", Name: "bad", Template: "{{.name"})
+	assert.Equal(t, 422, code)
+
+	code, campaign := call("POST", "/api/v1/campaign", in)
+	assert.Equal(t, 201, code)
+	assert.Equal(t, "RUNNING", campaign.Status)
+	id := campaign.CampaignUuid.String()
+	code, campaign = call("POST", "/api/v1/campaign/"+id+"/pause", nil)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "PAUSED", campaign.Status)
+	pending := campaign.Progress.Pending
+	assert.Equal(t, true, pending >= 3)
+	time.Sleep(300 * time.Millisecond)
+	_, campaign = call("GET", "/api/v1/campaign/"+id, nil)
+	assert.Equal(t, pending, campaign.Progress.Pending)
+	code, _ = call("POST", "/api/v1/campaign/"+id+"/pause", nil)
+	assert.Equal(t, 409, code)
+
+	code, _ = call("POST", "/api/v1/campaign/"+id+"/resume", nil)
+	assert.Equal(t, 200, code)
+	campaign = waitCampaign(id, func(campaign api.CampaignOut) bool {
+		return campaign.Status == "COMPLETED" && campaign.Progress.Delivered == 3
+	})
+	// the last recipient has no name for the template
+	assert.Equal(t, 1, campaign.Progress.Failed)
+	assert.Equal(t, 0, campaign.Progress.Pending)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/message/search?phoneNumber=81234567823", nil)
+	app.ServeHTTP(w, req)
+	messages := make([]api.ListMessageOut, 0)
+	json.Unmarshal(w.Body.Bytes(), &messages)
+	assert.Equal(t, "Hi Bob, spring sale starts today", messages[0].MessageText)
+
+	// aborting cancels messages which haven't gone out
+	in.Speed = 1
+	in.Recipients = in.Recipients[:3]
+	_, campaign = call("POST", "/api/v1/campaign", in)
+	id = campaign.CampaignUuid.String()
+	code, campaign = call("POST", "/api/v1/campaign/"+id+"/abort", nil)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "ABORTED", campaign.Status)
+	assert.Equal(t, 0, campaign.Progress.Pending)
+	// every recipient is counted
+	progress := campaign.Progress
+	assert.Equal(t, 3, progress.Skipped+progress.Queued+progress.Sent+progress.Delivered+progress.Failed)
+	code, _ = call("POST", "/api/v1/campaign/"+id+"/resume", nil)
+	assert.Equal(t, 409, code)
+	time.Sleep(100 * time.Millisecond)
+	_, campaign = call("GET", "/api/v1/campaign/"+id, nil)
+	assert.Equal(t, true, campaign.Progress.Delivered+campaign.Progress.Failed <= 1)
+
+	// a campaign of a deleted sender is aborted
+	gone := addSender(t, app, "campaign_gone", "123")
+	in.Login = "campaign_gone"
+	_, campaign = call("POST", "/api/v1/campaign", in)
+	id = campaign.CampaignUuid.String()
+	deleteEntity(t, app, "/api/v1/sender/"+gone.SenderUuid.String())
+	campaign = waitCampaign(id, func(campaign api.CampaignOut) bool {
+		return campaign.Status == "ABORTED"
+	})
+	assert.Equal(t, true, strings.Contains(campaign.LastError, "sender not found"))
+}
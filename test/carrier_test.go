@@ -0,0 +1,88 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/utils"
+	"testing"
+	"time"
+)
+
+func TestCarrierRouting(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,DELIVERED:10ms"
+	cfg.DefaultCountry = "RU"
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	addSender(t, app, "carrier", "123")
+	in := &api.MessageIn{
+		Login:       "carrier",
+		Password:    "123",
+		SenderName:  "CARRIER",
+		MessageType: "TEXT",
+		MessageText: "hello",
+		PhoneNumber: "89161234567",
+	}
+	setProfile := func(profile *api.CarrierProfileIn) int {
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(profile)
+		req, _ := http.NewRequest("PUT", "/api/v1/carrier/25001/profile", bytes.NewBuffer(body))
+		app.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	msg := sendMessage(t, app, in)
+	waitStatus(t, app, msg.MessageUuid, "DELIVERED")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/message?carrier=25001&limit=1", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	list := make([]api.ListMessageOut, 0)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, msg.MessageUuid, list[0].MessageUuid)
+	assert.Equal(t, "RU", list[0].Country)
+	assert.Equal(t, "250", list[0].Mcc)
+	assert.Equal(t, "01", list[0].Mnc)
+	assert.Equal(t, "MTS", list[0].Carrier)
+
+	assert.Equal(t, 422, setProfile(&api.CarrierProfileIn{FailureRate: 101}))
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/v1/carrier/99999/profile", bytes.NewBuffer([]byte(`{}`)))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+
+	assert.Equal(t, 200, setProfile(&api.CarrierProfileIn{FailureRate: 100}))
+	msg = sendMessage(t, app, in)
+	status := waitStatus(t, app, msg.MessageUuid, "UNDELIVERED")
+	assert.Equal(t, 34, status.ErrorCode)
+	assert.Equal(t, "carrier: MTS failure", status.Rule)
+
+	now := time.Now()
+	assert.Equal(t, 200, setProfile(&api.CarrierProfileIn{ErrorCode: 27, ErrorMessage: "absent subscriber",
+		Outages: []api.OutageInOut{{Start: now.Add(-time.Minute), End: now.Add(time.Minute)}}}))
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/carrier/25001", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	carrier := api.CarrierOut{}
+	json.Unmarshal(w.Body.Bytes(), &carrier)
+	assert.Equal(t, "MTS", carrier.Name)
+	assert.Equal(t, []string{"791", "798"}, carrier.Prefixes)
+	assert.Equal(t, 1, len(carrier.Profile.Outages))
+	msg = sendMessage(t, app, in)
+	status = waitStatus(t, app, msg.MessageUuid, "UNDELIVERED")
+	assert.Equal(t, 27, status.ErrorCode)
+	assert.Equal(t, "carrier: MTS outage", status.Rule)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/carrier/25001/profile", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+	msg = sendMessage(t, app, in)
+	waitStatus(t, app, msg.MessageUuid, "DELIVERED")
+}
@@ -0,0 +1,51 @@
+package api_test
+
+import (
+	"gopkg.in/go-playground/assert.v1"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"smsgate-mock/api"
+	"testing"
+	"time"
+)
+
+func TestChaos(t *testing.T) {
+	app := initApi(t)
+	defer app.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+	go app.Serve(l)
+	url := "http://" + l.Addr().String() + "/api/v1/sender"
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	for _, mode := range []string{"reset", "truncate"} {
+		fault := addFault(t, app, &api.FaultIn{Name: mode, Route: "/api/v1/sender", Method: "GET", Chaos: mode, ChaosRate: 100})
+		resp, err := client.Get(url)
+		if err == nil {
+			_, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		assert.NotEqual(t, nil, err)
+		deleteFault(t, app, fault)
+	}
+
+	fault := addFault(t, app, &api.FaultIn{Name: "slow", Route: "/api/v1/sender", Method: "GET", Chaos: "slow", ChaosRate: 100, DripBytes: 1, DripInterval: "10ms"})
+	start := time.Now()
+	resp, err := client.Get(url)
+	assert.Equal(t, nil, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, true, time.Since(start) >= time.Duration(len(body))*10*time.Millisecond)
+	deleteFault(t, app, fault)
+
+	resp, err = client.Get(url)
+	assert.Equal(t, nil, err)
+	_, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, nil, err)
+}
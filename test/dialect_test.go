@@ -0,0 +1,92 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/utils"
+	"testing"
+	"time"
+)
+
+func TestDialect(t *testing.T) {
+	callbacks := make(chan map[string]string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cb := make(map[string]string)
+		json.NewDecoder(r.Body).Decode(&cb)
+		callbacks <- cb
+	}))
+	defer srv.Close()
+
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,SENT:10ms,DELIVERED:100ms"
+	cfg.ProcessInterval = 10 * time.Millisecond
+	cfg.DialectsDir = "../dialects"
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	sender := addSender(t, app, "dialect", "key")
+
+	w := httptest.NewRecorder()
+	body := []byte(`{"destination":{"msisdn":"81234567899"},"body":"Hello dialect","originator":"ACME","notify_url":"` + srv.URL + `"}`)
+	req, _ := http.NewRequest("POST", "/example/v1/sms", bytes.NewBuffer(body))
+	req.Header.Set("X-Api-Login", "dialect")
+	req.Header.Set("X-Api-Key", "wrong")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Code)
+	assert.Equal(t, `{"error":"unauthorized"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/example/v1/sms", bytes.NewBufferString(`{"body":"No phone"}`))
+	req.Header.Set("X-Api-Login", "dialect")
+	req.Header.Set("X-Api-Key", "key")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 422, w.Code)
+	assert.Equal(t, `{"error":"destination.msisdn is required","field":"destination.msisdn"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/example/v1/sms", bytes.NewBuffer(body))
+	req.Header.Set("X-Api-Login", "dialect")
+	req.Header.Set("X-Api-Key", "key")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 202, w.Code)
+	sent := make(map[string]interface{})
+	json.Unmarshal(w.Body.Bytes(), &sent)
+	assert.Equal(t, "pending", sent["state"])
+	id := sent["id"].(string)
+
+	timeout := time.After(2 * time.Second)
+	for done := false; !done; {
+		select {
+		case cb := <-callbacks:
+			assert.Equal(t, id, cb["id"])
+			done = cb["state"] == "delivered"
+		case <-timeout:
+			t.Fatalf("no delivered callback")
+		}
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/example/v1/sms/"+id, nil)
+	req.Header.Set("X-Api-Login", "dialect")
+	req.Header.Set("X-Api-Key", "key")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	status := make(map[string]interface{})
+	json.Unmarshal(w.Body.Bytes(), &status)
+	assert.Equal(t, "delivered", status["state"])
+	assert.Equal(t, true, status["final"])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/example/v1/sms/"+sender.SenderUuid.String(), nil)
+	req.Header.Set("X-Api-Login", "dialect")
+	req.Header.Set("X-Api-Key", "key")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
@@ -0,0 +1,86 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"testing"
+)
+
+func addFault(t *testing.T, app *api.App, fault *api.FaultIn) api.FaultOut {
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(fault)
+	req, _ := http.NewRequest("POST", "/api/v1/admin/fault", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	res := api.FaultOut{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res
+}
+
+func deleteFault(t *testing.T, app *api.App, fault api.FaultOut) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/admin/fault/"+fault.FaultUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
+
+func TestFaults(t *testing.T) {
+	app := initApi(t)
+	defer app.Close()
+	sender := addSender(t, app, "faulty", "123")
+
+	throttled := addFault(t, app, &api.FaultIn{Name: "throttled", Route: "/api/v1/sender", Method: "get", ErrorRate: 100, ErrorStatuses: []int{429}})
+	malformed := addFault(t, app, &api.FaultIn{Name: "malformed", Route: "/api/v1/message", Login: "faulty", MalformedRate: 100})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/sender", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 429, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	body, _ := json.Marshal(&api.MessageIn{
+		Login:       "faulty",
+		Password:    "123",
+		SenderName:  "FAULTY",
+		MessageType: "TEXT",
+		MessageText: "Broken",
+		PhoneNumber: "81234567810",
+	})
+	req, _ = http.NewRequest("POST", "/api/v1/message", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	var msg map[string]interface{}
+	assert.NotEqual(t, nil, json.Unmarshal(w.Body.Bytes(), &msg))
+
+	w = httptest.NewRecorder()
+	body, _ = json.Marshal(&api.MaintenanceInOut{Enabled: true, RetryAfter: 30})
+	req, _ = http.NewRequest("PUT", "/api/v1/admin/maintenance", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/message/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 503, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	body, _ = json.Marshal(&api.MaintenanceInOut{Enabled: false})
+	req, _ = http.NewRequest("PUT", "/api/v1/admin/maintenance", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	deleteFault(t, app, throttled)
+	deleteFault(t, app, malformed)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
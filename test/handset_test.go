@@ -0,0 +1,85 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/utils"
+	"testing"
+	"time"
+)
+
+func TestHandsets(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,SENT:10ms,DELIVERED:10ms"
+	cfg.DefaultCountry = "RU"
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	addSender(t, app, "handset", "123")
+	setState := func(state string) api.HandsetOut {
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(&api.HandsetIn{State: state})
+		req, _ := http.NewRequest("PUT", "/api/v1/handset/89031234567", bytes.NewBuffer(body))
+		app.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		res := api.HandsetOut{}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return res
+	}
+	send := func(expiration int) api.MessageOut {
+		return sendMessage(t, app, &api.MessageIn{
+			Login:             "handset",
+			Password:          "123",
+			SenderName:        "HANDSET",
+			MessageType:       "TEXT",
+			MessageText:       "hello",
+			PhoneNumber:       "+79031234567",
+			ExpirationTimeout: expiration,
+		})
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/v1/handset/89031234567", bytes.NewBuffer([]byte(`{"state": "asleep"}`)))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 422, w.Code)
+
+	handset := setState("offline")
+	assert.Equal(t, "+79031234567", handset.PhoneNumber)
+	assert.Equal(t, "OFFLINE", handset.State)
+	msg := send(0)
+	status := waitStatus(t, app, msg.MessageUuid, "SENT")
+	for i := 0; i < 50 && !status.Held; i++ {
+		time.Sleep(20 * time.Millisecond)
+		status = waitStatus(t, app, msg.MessageUuid, "SENT")
+	}
+	assert.Equal(t, true, status.Held)
+	handset = setState("MEMORY_FULL")
+	assert.Equal(t, 1, handset.HeldMessages)
+	time.Sleep(100 * time.Millisecond)
+	waitStatus(t, app, msg.MessageUuid, "SENT")
+
+	// the stored message is forwarded when the handset is back
+	handset = setState("ONLINE")
+	assert.Equal(t, 0, handset.HeldMessages)
+	waitStatus(t, app, msg.MessageUuid, "DELIVERED")
+
+	setState("SWITCHED_OFF")
+	msg = send(1)
+	waitStatus(t, app, msg.MessageUuid, "EXPIRED")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/handset/+79031234567", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &handset)
+	assert.Equal(t, 0, handset.HeldMessages)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/handset/+79031234567", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+	msg = send(0)
+	waitStatus(t, app, msg.MessageUuid, "DELIVERED")
+}
@@ -0,0 +1,74 @@
+package api_test
+
+import (
+	"github.com/google/uuid"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"smsgate-mock/utils"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestKannel(t *testing.T) {
+	dlrs := make(chan url.Values, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dlrs <- r.URL.Query()
+	}))
+	defer srv.Close()
+
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,SENT:10ms,DELIVERED:100ms"
+	cfg.ProcessInterval = 10 * time.Millisecond
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	sender := addSender(t, app, "kannel", "secret")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cgi-bin/sendsms?username=kannel&password=wrong&to=81234567898&text=Hi", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, "Authorization failed for sendsms", w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/cgi-bin/sendsms?username=kannel&password=secret&text=Hi", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+
+	query := url.Values{
+		"username": {"kannel"},
+		"password": {"secret"},
+		"to":       {"81234567898"},
+		"from":     {"BILLING"},
+		"text":     {"Your bill is ready"},
+		"dlr-mask": {"3"},
+		"dlr-url":  {srv.URL + "/dlr?type=%d&to=%p&from=%P&id=%I&ts=%T"},
+	}
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/cgi-bin/sendsms?"+query.Encode(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 202, w.Code)
+	assert.Equal(t, "0: Accepted for delivery", w.Body.String())
+	id := w.Header().Get("X-Kannel-Message-Id")
+
+	// only final statuses are in dlr-mask 3
+	select {
+	case dlr := <-dlrs:
+		assert.Equal(t, "1", dlr.Get("type"))
+		assert.Equal(t, "81234567898", dlr.Get("to"))
+		assert.Equal(t, "BILLING", dlr.Get("from"))
+		assert.Equal(t, id, dlr.Get("id"))
+		// %T is the time of the status change
+		status := waitStatus(t, app, uuid.MustParse(id), "DELIVERED")
+		assert.Equal(t, strconv.FormatInt(status.Updated.Unix(), 10), dlr.Get("ts"))
+	case <-time.After(2 * time.Second):
+		t.Fatalf("dlr-url wasn't called")
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
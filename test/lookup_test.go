@@ -0,0 +1,104 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/utils"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,DELIVERED:10ms"
+	cfg.DefaultCountry = "DE"
+	cfg.SubscriberCheck = true
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	addSender(t, app, "lookup", "123")
+	setSubscriber := func(number string, subscriber *api.SubscriberIn) int {
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(subscriber)
+		req, _ := http.NewRequest("PUT", "/api/v1/subscriber/"+number, bytes.NewBuffer(body))
+		app.ServeHTTP(w, req)
+		return w.Code
+	}
+	lookup := func(number string) api.LookupOut {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/lookup/"+number, nil)
+		app.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		res := api.LookupOut{}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return res
+	}
+	send := func(number string) api.MessageOut {
+		return sendMessage(t, app, &api.MessageIn{
+			Login:       "lookup",
+			Password:    "123",
+			SenderName:  "LOOKUP",
+			MessageType: "TEXT",
+			MessageText: "code 1234",
+			PhoneNumber: number,
+		})
+	}
+
+	assert.Equal(t, 422, setSubscriber("015112345672", &api.SubscriberIn{LineType: "PAGER"}))
+	assert.Equal(t, 422, setSubscriber("015112345672", &api.SubscriberIn{PortedTo: "99999"}))
+	assert.Equal(t, 200, setSubscriber("015112345672", &api.SubscriberIn{PortedTo: "26202", Roaming: "fr"}))
+	assert.Equal(t, 200, setSubscriber("+4915112345673", &api.SubscriberIn{Status: "absent"}))
+	assert.Equal(t, 200, setSubscriber("+4915112345674", &api.SubscriberIn{Status: "UNKNOWN"}))
+	assert.Equal(t, 200, setSubscriber("+493012345675", &api.SubscriberIn{LineType: "LANDLINE"}))
+
+	res := lookup("+4915112345672")
+	assert.Equal(t, true, res.Valid)
+	assert.Equal(t, "DE", res.Country)
+	assert.Equal(t, "MOBILE", res.LineType)
+	assert.Equal(t, "Telekom", res.OriginalCarrier.Name)
+	assert.Equal(t, true, res.Ported)
+	assert.Equal(t, "Vodafone", res.PortedCarrier.Name)
+	assert.Equal(t, true, res.Roaming)
+	assert.Equal(t, "FR", res.RoamingCountry)
+	assert.Equal(t, true, res.Reachable)
+	res = lookup("+493012345675")
+	assert.Equal(t, "LANDLINE", res.LineType)
+	assert.Equal(t, (*api.LookupCarrierOut)(nil), res.OriginalCarrier)
+	res = lookup("+4915112345673")
+	assert.Equal(t, true, res.Valid)
+	assert.Equal(t, "ABSENT", res.Status)
+	assert.Equal(t, false, res.Reachable)
+	res = lookup("+4915112345674")
+	assert.Equal(t, false, res.Valid)
+	res = lookup("12")
+	assert.Equal(t, false, res.Valid)
+	assert.Equal(t, "", res.PhoneNumber)
+
+	// messages are routed to the ported carrier and fail for absent and unknown subscribers
+	msg := send("+4915112345672")
+	waitStatus(t, app, msg.MessageUuid, "DELIVERED")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/message?carrier=Vodafone&limit=1", nil)
+	app.ServeHTTP(w, req)
+	list := make([]api.ListMessageOut, 0)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, msg.MessageUuid, list[0].MessageUuid)
+	msg = send("+4915112345673")
+	status := waitStatus(t, app, msg.MessageUuid, "UNDELIVERED")
+	assert.Equal(t, 27, status.ErrorCode)
+	msg = send("+4915112345674")
+	status = waitStatus(t, app, msg.MessageUuid, "UNDELIVERED")
+	assert.Equal(t, 1, status.ErrorCode)
+
+	for _, number := range []string{"+4915112345672", "+4915112345673", "+4915112345674", "+493012345675"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("DELETE", "/api/v1/subscriber/"+number, nil)
+		app.ServeHTTP(w, req)
+		assert.Equal(t, 204, w.Code)
+	}
+	msg = send("+4915112345673")
+	waitStatus(t, app, msg.MessageUuid, "DELIVERED")
+}
@@ -0,0 +1,127 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/utils"
+	"testing"
+)
+
+func TestQuota(t *testing.T) {
+	app := initApi(t)
+	defer app.Close()
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(&api.SenderIn{Login: "limited", Password: "123", RateLimit: 0.01, RateBurst: 2, DailyQuota: 3})
+	req, _ := http.NewRequest("POST", "/api/v1/sender", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	sender := api.SenderOut{}
+	json.Unmarshal(w.Body.Bytes(), &sender)
+
+	send := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(&api.MessageIn{
+			Login:       "limited",
+			Password:    "123",
+			SenderName:  "LIMITED",
+			MessageType: "TEXT",
+			MessageText: "Burst",
+			PhoneNumber: "81234567811",
+		})
+		req, _ := http.NewRequest("POST", "/api/v1/message", bytes.NewBuffer(body))
+		app.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, 201, send().Code)
+	assert.Equal(t, 201, send().Code)
+	w = send()
+	assert.Equal(t, 429, w.Code)
+	assert.NotEqual(t, "", w.Header().Get("Retry-After"))
+	limit := api.LimitErrorMessage{}
+	json.Unmarshal(w.Body.Bytes(), &limit)
+	assert.Equal(t, api.CodeThrottled, limit.Code)
+
+	w = httptest.NewRecorder()
+	body, _ = json.Marshal(&api.SenderEditIn{SenderUuid: sender.SenderUuid, RateLimit: -1})
+	req, _ = http.NewRequest("PATCH", "/api/v1/sender/"+sender.SenderUuid.String(), bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+
+	assert.Equal(t, 201, send().Code)
+	w = send()
+	assert.Equal(t, 429, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &limit)
+	assert.Equal(t, api.CodeDailyQuotaExceeded, limit.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/sender/"+sender.SenderUuid.String()+"/quota", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	quota := api.QuotaOut{}
+	json.Unmarshal(w.Body.Bytes(), &quota)
+	assert.Equal(t, 3, quota.DailyQuota)
+	assert.Equal(t, 3, quota.DailyUsed)
+	assert.Equal(t, 0, quota.DailyRemaining)
+	assert.Equal(t, -1, quota.MonthlyRemaining)
+	assert.Equal(t, float64(-1), quota.Tokens)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
+
+func TestQuotaRelease(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.DefaultCountry = "RU"
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	prepaid := true
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(&api.SenderIn{Login: "released", Password: "123", DailyQuota: 5, Prepaid: &prepaid})
+	req, _ := http.NewRequest("POST", "/api/v1/sender", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	sender := api.SenderOut{}
+	json.Unmarshal(w.Body.Bytes(), &sender)
+	defer deleteEntity(t, app, "/api/v1/sender/"+sender.SenderUuid.String())
+
+	w = httptest.NewRecorder()
+	body, _ = json.Marshal(&api.PriceIn{Prefix: "+71234567822", PerSegment: 0.05})
+	req, _ = http.NewRequest("POST", "/api/v1/price", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	price := api.PriceOut{}
+	json.Unmarshal(w.Body.Bytes(), &price)
+	defer deleteEntity(t, app, "/api/v1/price/"+price.PriceUuid.String())
+
+	w = httptest.NewRecorder()
+	body, _ = json.Marshal(&api.TopUpIn{Amount: 0.05, Description: "test"})
+	req, _ = http.NewRequest("POST", "/api/v1/account/"+sender.SenderUuid.String()+"/topup", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+
+	// both messages pass the balance check, the second one can't be charged
+	w = httptest.NewRecorder()
+	body, _ = json.Marshal(&api.BatchIn{Login: "released", Password: "123", SenderName: "RELEASED", MessageType: "TEXT",
+		MessageText: "hello", PhoneNumbers: []string{"81234567822", "81234567822"}})
+	req, _ = http.NewRequest("POST", "/api/v1/batch", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	res := api.BatchOut{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	assert.Equal(t, 1, res.Accepted)
+	assert.Equal(t, api.CodeInsufficientBalance, res.Results[1].Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/sender/"+sender.SenderUuid.String()+"/quota", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	quota := api.QuotaOut{}
+	json.Unmarshal(w.Body.Bytes(), &quota)
+	assert.Equal(t, 1, quota.DailyUsed)
+}
@@ -0,0 +1,90 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/utils"
+	"testing"
+	"time"
+)
+
+func TestRules(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,SENT:10ms,DELIVERED:10ms"
+	cfg.ProcessInterval = 10 * time.Millisecond
+	cfg.MagicNumbers = true
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	sender := addSender(t, app, "rules", "123")
+
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(&api.RuleIn{Name: "bad status", PhonePrefix: "7999", Status: "SENT"})
+	req, _ := http.NewRequest("POST", "/api/v1/rule", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 422, w.Code)
+
+	w = httptest.NewRecorder()
+	body, _ = json.Marshal(&api.RuleIn{
+		Name:         "blocked text",
+		PhonePrefix:  "7999",
+		TextRegex:    "(?i)casino",
+		Login:        "rules",
+		Status:       "rejected",
+		ErrorCode:    88,
+		ErrorMessage: "spam",
+	})
+	req, _ = http.NewRequest("POST", "/api/v1/rule", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	rule := api.RuleOut{}
+	json.Unmarshal(w.Body.Bytes(), &rule)
+	assert.Equal(t, "REJECTED", rule.Status)
+
+	msg := sendMessage(t, app, &api.MessageIn{
+		Login:       "rules",
+		Password:    "123",
+		SenderName:  "RULES",
+		MessageType: "TEXT",
+		MessageText: "Best CASINO in town",
+		PhoneNumber: "79990000000",
+	})
+	status := waitStatus(t, app, msg.MessageUuid, "REJECTED")
+	assert.Equal(t, 88, status.ErrorCode)
+	assert.Equal(t, "spam", status.ErrorMessage)
+	assert.Equal(t, "blocked text", status.Rule)
+
+	msg = sendMessage(t, app, &api.MessageIn{
+		Login:       "rules",
+		Password:    "123",
+		SenderName:  "RULES",
+		MessageType: "TEXT",
+		MessageText: "Hello",
+		PhoneNumber: "79990000001",
+	})
+	status = waitStatus(t, app, msg.MessageUuid, "UNDELIVERED")
+	assert.Equal(t, 1, status.ErrorCode)
+	assert.Equal(t, "unknown subscriber", status.ErrorMessage)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/rule", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var rules []*api.RuleOut
+	json.Unmarshal(w.Body.Bytes(), &rules)
+	assert.Equal(t, rule.RuleUuid, rules[0].RuleUuid)
+	assert.Equal(t, true, rules[len(rules)-1].Builtin)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/rule/"+rule.RuleUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
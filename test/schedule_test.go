@@ -0,0 +1,71 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/data"
+	"smsgate-mock/utils"
+	"testing"
+	"time"
+)
+
+func TestScheduledMessages(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,DELIVERED:10ms"
+	app := initApiWithSettings(t, cfg)
+	addSender(t, app, "schedule", "123")
+	send := func(app *api.App, sendAt time.Time) api.MessageOut {
+		return sendMessage(t, app, &api.MessageIn{
+			Login:       "schedule",
+			Password:    "123",
+			SenderName:  "SCHEDULE",
+			MessageType: "TEXT",
+			MessageText: "sale starts now",
+			PhoneNumber: "81234567817",
+			SendAt:      &sendAt,
+		})
+	}
+	cancel := func(app *api.App, msg api.MessageOut) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/message/"+msg.MessageUuid.String()+"/cancel", nil)
+		app.ServeHTTP(w, req)
+		return w
+	}
+
+	later := send(app, time.Now().Add(time.Hour))
+	assert.Equal(t, "SCHEDULED", later.Status)
+	soon := send(app, time.Now().Add(300*time.Millisecond))
+	assert.Equal(t, "SCHEDULED", soon.Status)
+
+	// the schedule is kept in the database across restarts
+	app.Close()
+	app = initApiWithSettings(t, cfg)
+	defer app.Close()
+	status := waitStatus(t, app, soon.MessageUuid, "DELIVERED")
+	assert.Equal(t, false, status.Done.Before(*soon.SendAt))
+
+	w := cancel(app, later)
+	assert.Equal(t, 200, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &status)
+	assert.Equal(t, "CANCELED", status.Status)
+	assert.Equal(t, 409, cancel(app, later).Code)
+	assert.Equal(t, 409, cancel(app, soon).Code)
+}
+
+func TestRequestedStatusesArePlannedNowhere(t *testing.T) {
+	for _, lifecycle := range []string{"CANCELED", "SCHEDULED:1s,DELIVERED:1s", "ACCEPTED:1s,CANCELED"} {
+		_, err := data.ParseLifecycle(lifecycle)
+		assert.NotEqual(t, nil, err)
+	}
+	app := initApi(t)
+	defer app.Close()
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(&api.RuleIn{Name: "canceled", PhonePrefix: "7999", Status: "CANCELED"})
+	req, _ := http.NewRequest("POST", "/api/v1/rule", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 422, w.Code)
+}
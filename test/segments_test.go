@@ -0,0 +1,69 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/utils"
+	"strings"
+	"testing"
+)
+
+func TestSegments(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.MaxParts = 3
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	sender := addSender(t, app, "segments", "123")
+	send := func(text string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(&api.MessageIn{
+			Login:       "segments",
+			Password:    "123",
+			SenderName:  "SEGMENTS",
+			MessageType: "TEXT",
+			MessageText: text,
+			PhoneNumber: "81234567812",
+		})
+		req, _ := http.NewRequest("POST", "/api/v1/message", bytes.NewBuffer(body))
+		app.ServeHTTP(w, req)
+		return w
+	}
+
+	cases := []struct {
+		text     string
+		encoding string
+		parts    []int
+	}{
+		{strings.Repeat("a", 160), "GSM7", []int{160}},
+		{strings.Repeat("a", 161), "GSM7", []int{153, 8}},
+		// escaped characters take two septets and aren't split
+		{strings.Repeat("a", 152) + "€" + strings.Repeat("a", 10), "GSM7", []int{152, 11}},
+		{strings.Repeat("я", 70), "UCS2", []int{70}},
+		// emoji is a surrogate pair
+		{strings.Repeat("я", 66) + "😀" + "яяя", "UCS2", []int{66, 4}},
+	}
+	for _, tc := range cases {
+		w := send(tc.text)
+		assert.Equal(t, 201, w.Code)
+		msg := api.MessageOut{}
+		json.Unmarshal(w.Body.Bytes(), &msg)
+		assert.Equal(t, tc.encoding, msg.Encoding)
+		assert.Equal(t, len(tc.parts), msg.Segments)
+		for i, part := range msg.Parts {
+			assert.Equal(t, tc.parts[i], len([]rune(part)))
+		}
+		assert.Equal(t, tc.text, strings.Join(msg.Parts, ""))
+	}
+
+	w := send(strings.Repeat("a", 153*3+1))
+	assert.Equal(t, 422, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
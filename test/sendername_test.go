@@ -0,0 +1,92 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/utils"
+	"testing"
+)
+
+func TestSenderNameRegistry(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.SenderNameRegistry = true
+	cfg.DefaultCountry = "RU"
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	sender := addSender(t, app, "registry", "123")
+	send := func(name, phone string) (*httptest.ResponseRecorder, api.SenderNameErrorMessage) {
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(&api.MessageIn{
+			Login:       "registry",
+			Password:    "123",
+			SenderName:  name,
+			MessageType: "TEXT",
+			MessageText: "hello",
+			PhoneNumber: phone,
+		})
+		req, _ := http.NewRequest("POST", "/api/v1/message", bytes.NewBuffer(body))
+		app.ServeHTTP(w, req)
+		res := api.SenderNameErrorMessage{}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w, res
+	}
+
+	w, res := send("ACME", "81234567815")
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, api.CodeSenderNameNotRegistered, res.Code)
+
+	w = httptest.NewRecorder()
+	body, _ := json.Marshal(&api.SenderNameIn{SenderUuid: sender.SenderUuid, Name: "ACME", Countries: []string{"ru", "kz"}})
+	req, _ := http.NewRequest("POST", "/api/v1/sender_name", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	name := api.SenderNameOut{}
+	json.Unmarshal(w.Body.Bytes(), &name)
+	assert.Equal(t, "PENDING", name.Status)
+	assert.Equal(t, []string{"RU", "KZ"}, name.Countries)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/sender_name", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 409, w.Code)
+
+	w, res = send("ACME", "81234567815")
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, api.CodeSenderNameNotApproved, res.Code)
+
+	w = httptest.NewRecorder()
+	body, _ = json.Marshal(&api.SenderNameEditIn{Status: "approved"})
+	req, _ = http.NewRequest("PATCH", "/api/v1/sender_name/"+name.SenderNameUuid.String(), bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &name)
+	assert.Equal(t, "APPROVED", name.Status)
+	assert.Equal(t, []string{"RU", "KZ"}, name.Countries)
+
+	w, _ = send("ACME", "81234567815")
+	assert.Equal(t, 201, w.Code)
+	w, res = send("ACME", "+442079460958")
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, api.CodeSenderNameNotAllowed, res.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/sender_name?senderUuid="+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	list := make([]api.SenderNameOut, 0)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Equal(t, 1, len(list))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender_name/"+name.SenderNameUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
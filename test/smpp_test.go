@@ -0,0 +1,135 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/smpp"
+	"smsgate-mock/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func smppCall(t *testing.T, conn net.Conn, p *smpp.PDU) *smpp.PDU {
+	if _, err := conn.Write(p.Bytes()); err != nil {
+		t.Fatalf("can't write PDU: %v", err)
+	}
+	return smppRead(t, conn)
+}
+
+func smppRead(t *testing.T, conn net.Conn) *smpp.PDU {
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	resp, err := smpp.ReadPDU(conn)
+	if err != nil {
+		t.Fatalf("can't read PDU: %v", err)
+	}
+	return resp
+}
+
+func TestSmpp(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,DELIVERED:200ms"
+	cfg.ProcessInterval = 10 * time.Millisecond
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	sender := addSender(t, app, "smpp", "123")
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+	go app.ServeSmpp(l)
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("can't connect: %v", err)
+	}
+	defer conn.Close()
+
+	resp := smppCall(t, conn, &smpp.PDU{CommandId: smpp.SubmitSm, Sequence: 1, Body: (&smpp.ShortMessage{}).Encode()})
+	assert.Equal(t, smpp.StatusInvBndSts, resp.Status)
+	bind := &smpp.Bind{SystemId: "smpp", Password: "wrong", InterfaceVersion: 0x34}
+	resp = smppCall(t, conn, &smpp.PDU{CommandId: smpp.BindTransceiver, Sequence: 2, Body: bind.Encode()})
+	assert.Equal(t, smpp.StatusInvPaswd, resp.Status)
+	bind.Password = "123"
+	resp = smppCall(t, conn, &smpp.PDU{CommandId: smpp.BindTransceiver, Sequence: 3, Body: bind.Encode()})
+	assert.Equal(t, smpp.BindTransceiverResp, resp.CommandId)
+	assert.Equal(t, smpp.StatusOk, resp.Status)
+
+	sm := &smpp.ShortMessage{
+		Source:             "SMPP",
+		Destination:        "81234567897",
+		RegisteredDelivery: 1,
+		Message:            []byte("Hello SMPP"),
+	}
+	resp = smppCall(t, conn, &smpp.PDU{CommandId: smpp.SubmitSm, Sequence: 4, Body: sm.Encode()})
+	assert.Equal(t, smpp.SubmitSmResp, resp.CommandId)
+	assert.Equal(t, smpp.StatusOk, resp.Status)
+	messageId := smpp.NewReader(resp.Body).CString()
+
+	query := &smpp.QueryMessage{MessageId: messageId, Source: "SMPP"}
+	resp = smppCall(t, conn, &smpp.PDU{CommandId: smpp.QuerySm, Sequence: 5, Body: query.Encode()})
+	assert.Equal(t, smpp.StatusOk, resp.Status)
+	result := &smpp.QueryResult{}
+	result.Decode(resp.Body)
+	assert.Equal(t, messageId, result.MessageId)
+
+	dlr := smppRead(t, conn)
+	assert.Equal(t, smpp.DeliverSm, dlr.CommandId)
+	report := &smpp.ShortMessage{}
+	assert.Equal(t, nil, report.Decode(dlr.Body))
+	assert.Equal(t, smpp.EsmClassReceipt, report.EsmClass)
+	assert.Equal(t, messageId, smpp.NewReader(report.TLVs[smpp.TagReceiptedMessageId]).CString())
+	assert.Equal(t, true, strings.Contains(string(report.Message), "stat:DELIVRD"))
+	conn.Write(dlr.Response(smpp.StatusOk, []byte{0}).Bytes())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/message/search?phoneNumber=81234567897", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var msglist []*api.ListMessageOut
+	json.Unmarshal(w.Body.Bytes(), &msglist)
+	assert.Equal(t, 1, len(msglist))
+	assert.Equal(t, messageId, msglist[0].MessageUuid.String())
+	assert.Equal(t, "Hello SMPP", msglist[0].MessageText)
+	assert.Equal(t, "DELIVERED", msglist[0].Status)
+
+	w = httptest.NewRecorder()
+	body, _ := json.Marshal(&api.InboundIn{From: "81234567897", To: "SMPP", Text: "YES"})
+	req, _ = http.NewRequest("POST", "/api/v1/inbound", bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	mo := smppRead(t, conn)
+	assert.Equal(t, smpp.DeliverSm, mo.CommandId)
+	inbound := &smpp.ShortMessage{}
+	inbound.Decode(mo.Body)
+	assert.Equal(t, "81234567897", inbound.Source)
+	assert.Equal(t, "YES", string(inbound.Message))
+	conn.Write(mo.Response(smpp.StatusOk, []byte{0}).Bytes())
+
+	// sender changes after bind apply to the next submit
+	w = httptest.NewRecorder()
+	body, _ = json.Marshal(&api.SenderEditIn{SenderUuid: sender.SenderUuid, DailyQuota: 1})
+	req, _ = http.NewRequest("PATCH", "/api/v1/sender/"+sender.SenderUuid.String(), bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+	resp = smppCall(t, conn, &smpp.PDU{CommandId: smpp.SubmitSm, Sequence: 6, Body: sm.Encode()})
+	assert.Equal(t, smpp.StatusMsgQFul, resp.Status)
+
+	resp = smppCall(t, conn, &smpp.PDU{CommandId: smpp.EnquireLink, Sequence: 7})
+	assert.Equal(t, smpp.EnquireLinkResp, resp.CommandId)
+	resp = smppCall(t, conn, &smpp.PDU{CommandId: smpp.Unbind, Sequence: 8})
+	assert.Equal(t, smpp.UnbindResp, resp.CommandId)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/message/"+messageId, nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
@@ -0,0 +1,81 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"strings"
+	"testing"
+)
+
+func TestTransliteration(t *testing.T) {
+	app := initApi(t)
+	defer app.Close()
+	sender := addSender(t, app, "translit", "123")
+	on, off := true, false
+
+	// per-message option
+	text := strings.Repeat("Привет, мир! ", 6)
+	msg := sendMessage(t, app, &api.MessageIn{
+		Login:         "translit",
+		Password:      "123",
+		SenderName:    "TRANSLIT",
+		MessageType:   "TEXT",
+		MessageText:   text,
+		PhoneNumber:   "81234567813",
+		Transliterate: &on,
+	})
+	assert.Equal(t, "GSM7", msg.Encoding)
+	assert.Equal(t, text, msg.OriginalText)
+	assert.Equal(t, strings.Repeat("Privet, mir! ", 6), msg.MessageText)
+	assert.Equal(t, 1, msg.Segments)
+	assert.Equal(t, 1, msg.SegmentsSaved)
+
+	// accents in GSM 03.38 are kept, others are dropped, typography is replaced
+	msg = sendMessage(t, app, &api.MessageIn{
+		Login:         "translit",
+		Password:      "123",
+		SenderName:    "TRANSLIT",
+		MessageType:   "TEXT",
+		MessageText:   "Café «Zürich» — Łódź, Αθήνα…",
+		PhoneNumber:   "81234567813",
+		Transliterate: &on,
+	})
+	assert.Equal(t, "Café \"Zürich\" - Lodz, AΘHNA...", msg.MessageText)
+	assert.Equal(t, 0, msg.SegmentsSaved)
+
+	// sender's option is used unless the message overrides it
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(&api.SenderEditIn{SenderUuid: sender.SenderUuid, Transliterate: &on})
+	req, _ := http.NewRequest("PATCH", "/api/v1/sender/"+sender.SenderUuid.String(), bytes.NewBuffer(body))
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+	msg = sendMessage(t, app, &api.MessageIn{
+		Login:       "translit",
+		Password:    "123",
+		SenderName:  "TRANSLIT",
+		MessageType: "TEXT",
+		MessageText: "Ελλάδα",
+		PhoneNumber: "81234567813",
+	})
+	assert.Equal(t, "EΛΛAΔA", msg.MessageText)
+	msg = sendMessage(t, app, &api.MessageIn{
+		Login:         "translit",
+		Password:      "123",
+		SenderName:    "TRANSLIT",
+		MessageType:   "TEXT",
+		MessageText:   "Ελλάδα",
+		PhoneNumber:   "81234567813",
+		Transliterate: &off,
+	})
+	assert.Equal(t, "UCS2", msg.Encoding)
+	assert.Equal(t, "", msg.OriginalText)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
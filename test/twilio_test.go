@@ -0,0 +1,105 @@
+package api_test
+
+import (
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"smsgate-mock/api"
+	"smsgate-mock/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTwilio(t *testing.T) {
+	callbacks := make(chan url.Values, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if len(r.Header.Get("X-Twilio-Signature")) == 0 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		callbacks <- r.PostForm
+	}))
+	defer srv.Close()
+
+	cfg := utils.ReadSettings()
+	cfg.Lifecycle = "ACCEPTED:10ms,SENT:10ms,DELIVERED:100ms"
+	cfg.ProcessInterval = 10 * time.Millisecond
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	sender := addSender(t, app, "twilio", "token")
+	base := "/2010-04-01/Accounts/twilio"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", base+"/Messages.json", nil)
+	req.SetBasicAuth("twilio", "wrong")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Code)
+	twErr := api.TwilioError{}
+	json.Unmarshal(w.Body.Bytes(), &twErr)
+	assert.Equal(t, 20003, twErr.Code)
+
+	w = httptest.NewRecorder()
+	form := url.Values{"To": {"+15005550009"}, "From": {"+15005550001"}}
+	req, _ = http.NewRequest("POST", base+"/Messages.json", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("twilio", "token")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &twErr)
+	assert.Equal(t, 21602, twErr.Code)
+
+	w = httptest.NewRecorder()
+	form.Set("Body", "Hello from Twilio")
+	form.Set("StatusCallback", srv.URL)
+	req, _ = http.NewRequest("POST", base+"/Messages.json", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("twilio", "token")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	msg := api.TwilioMessageOut{}
+	json.Unmarshal(w.Body.Bytes(), &msg)
+	assert.Equal(t, "queued", msg.Status)
+	assert.Equal(t, "twilio", msg.AccountSid)
+	assert.Equal(t, "+15005550009", msg.To)
+	assert.Equal(t, cfg.Currency, msg.PriceUnit)
+
+	statuses := []string{}
+	timeout := time.After(2 * time.Second)
+	for len(statuses) == 0 || statuses[len(statuses)-1] != "delivered" {
+		select {
+		case cb := <-callbacks:
+			assert.Equal(t, msg.Sid, cb.Get("MessageSid"))
+			statuses = append(statuses, cb.Get("MessageStatus"))
+		case <-timeout:
+			t.Fatalf("no delivered status callback, got %v", statuses)
+		}
+	}
+	assert.Equal(t, []string{"sending", "sent", "delivered"}, statuses)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", base+"/Messages/"+msg.Sid+".json", nil)
+	req.SetBasicAuth("twilio", "token")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &msg)
+	assert.Equal(t, "delivered", msg.Status)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", base+"/Messages.json?To=%2B15005550009&PageSize=1", nil)
+	req.SetBasicAuth("twilio", "token")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	list := api.TwilioMessageListOut{}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Equal(t, 1, len(list.Messages))
+	assert.Equal(t, msg.Sid, list.Messages[0].Sid)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
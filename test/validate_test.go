@@ -0,0 +1,103 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/go-playground/assert.v1"
+	"net/http"
+	"net/http/httptest"
+	"smsgate-mock/api"
+	"smsgate-mock/phone"
+	"smsgate-mock/utils"
+	"testing"
+)
+
+func TestPhoneNormalize(t *testing.T) {
+	cases := []struct {
+		number  string
+		country string
+		e164    string
+	}{
+		{"8 (123) 456-78-14", "RU", "+71234567814"},
+		{"71234567814", "RU", "+71234567814"},
+		{"1234567814", "RU", "+71234567814"},
+		{"+44 20 7946 0958", "RU", "+442079460958"},
+		{"0044 20 7946 0958", "US", "+442079460958"},
+		{"020 7946 0958", "GB", "+442079460958"},
+		{"(415) 555-2671", "US", "+14155552671"},
+		{"+999 1234 5678", "RU", "+99912345678"},
+		{"+7 123", "RU", ""},
+		{"+1234567890123456", "RU", ""},
+		{"81234a67814", "RU", ""},
+		{"12345", "RU", ""},
+		{"1234567814", "XX", ""},
+	}
+	for _, tc := range cases {
+		e164, err := phone.Normalize(tc.number, tc.country)
+		assert.Equal(t, tc.e164, e164)
+		assert.Equal(t, tc.e164 == "", err != nil)
+	}
+}
+
+func TestStrictValidation(t *testing.T) {
+	cfg := utils.ReadSettings()
+	cfg.StrictValidation = true
+	cfg.DefaultCountry = "RU"
+	app := initApiWithSettings(t, cfg)
+	defer app.Close()
+	sender := addSender(t, app, "strict", "123")
+	send := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/message", bytes.NewBufferString(body))
+		app.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(`{"login": "strict", "password": "123", "senderName": "STRICT", "messageType": "TEXT",
+		"messageText": "hello", "phoneNumber": "8 (123) 456-78-14"}`)
+	assert.Equal(t, 201, w.Code)
+	msg := api.MessageOut{}
+	json.Unmarshal(w.Body.Bytes(), &msg)
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/message/search?phoneNumber=%2B71234567814", nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	list := make([]api.ListMessageOut, 0)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, msg.MessageUuid, list[0].MessageUuid)
+
+	w = send(`{"login": "strict", "password": "123", "senderName": "TOO LONG NAME", "messageType": "SMS",
+		"phoneNumber": "+7 123", "expirationTimeout": "10", "priority": 1}`)
+	assert.Equal(t, 422, w.Code)
+	res := api.ValidationErrorMessage{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	codes := make(map[string]string)
+	for _, f := range res.Fields {
+		codes[f.Field] = f.Code
+	}
+	assert.Equal(t, map[string]string{
+		"expirationTimeout": api.FieldType,
+		"priority":          api.FieldUnknown,
+		"senderName":        api.FieldTooLong,
+		"messageType":       api.FieldInvalid,
+		"messageText":       api.FieldRequired,
+		"phoneNumber":       api.FieldInvalid,
+	}, codes)
+
+	// numeric sender IDs
+	w = send(`{"login": "strict", "password": "123", "senderName": "+123456789012345", "messageType": "FLASH",
+		"messageText": "hello", "phoneNumber": "+71234567814"}`)
+	assert.Equal(t, 201, w.Code)
+	w = send(`{"login": "strict", "password": "123", "senderName": "1234567890123456", "messageType": "FLASH",
+		"messageText": "hello", "phoneNumber": "+71234567814"}`)
+	assert.Equal(t, 422, w.Code)
+
+	w = send(`["not", "an", "object"]`)
+	assert.Equal(t, 422, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/sender/"+sender.SenderUuid.String(), nil)
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/caarlos0/env/v6"
 	"github.com/joho/godotenv"
 	"log"
+	"time"
 )
 
 func init() {
@@ -19,9 +20,46 @@ func init() {
 
 type Settings struct {
 	ListenPort int    `env:"LISTEN_PORT"`
+	// SmppPort enables SMPP server if not zero
+	SmppPort int `env:"SMPP_PORT"`
 	DbPath     string `env:"DB_PATH"`
 	LogRequest bool `env:"LOG_REQUEST"`
 	LogResponse bool `env:"LOG_RESPONSE"`
+	// Lifecycle is a list of statuses with delays a message goes through after QUEUED
+	Lifecycle       string        `env:"LIFECYCLE" envDefault:"ACCEPTED:1s,SENT:1s,DELIVERED:2s"`
+	ProcessInterval time.Duration `env:"PROCESS_INTERVAL" envDefault:"200ms"`
+	CallbackTimeout time.Duration `env:"CALLBACK_TIMEOUT" envDefault:"5s"`
+	// webhooks are retried with exponential backoff, after the last attempt they go to dead letters
+	WebhookMaxAttempts int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"5"`
+	WebhookBackoff     time.Duration `env:"WEBHOOK_BACKOFF" envDefault:"1s"`
+	WebhookMaxBackoff  time.Duration `env:"WEBHOOK_MAX_BACKOFF" envDefault:"5m"`
+	// MaxInFlight caps concurrent API requests, up to MaxQueue more wait for QueueTimeout,
+	// the rest get 503 with Retry-After; zero MaxInFlight means unlimited
+	MaxInFlight        int           `env:"MAX_IN_FLIGHT" envDefault:"0"`
+	MaxQueue           int           `env:"MAX_QUEUE" envDefault:"0"`
+	QueueTimeout       time.Duration `env:"QUEUE_TIMEOUT" envDefault:"5s"`
+	OverloadRetryAfter time.Duration `env:"OVERLOAD_RETRY_AFTER" envDefault:"1s"`
+	// MaxParts limits the number of parts of a concatenated message, zero means unlimited
+	MaxParts int `env:"MAX_PARTS" envDefault:"10"`
+	// MagicNumbers enables built-in rules by phone number suffix, like 0001 for unknown subscriber
+	MagicNumbers bool `env:"MAGIC_NUMBERS" envDefault:"false"`
+	// StrictValidation rejects messages with unknown or missing fields, invalid phone numbers, sender names
+	// and message types; numbers without a country code are read in DefaultCountry numbering plan
+	StrictValidation bool   `env:"STRICT_VALIDATION" envDefault:"false"`
+	DefaultCountry   string `env:"DEFAULT_COUNTRY" envDefault:"RU"`
+	// SenderNameRegistry rejects messages with sender names which aren't registered and approved for the sender
+	SenderNameRegistry bool `env:"SENDER_NAME_REGISTRY" envDefault:"false"`
+	// DefaultPrice of a segment is charged when no price matches the destination, failed messages are
+	// refunded if RefundFailed is set; Currency is reported with balances and prices
+	DefaultPrice float64 `env:"DEFAULT_PRICE" envDefault:"0"`
+	RefundFailed bool    `env:"REFUND_FAILED" envDefault:"true"`
+	Currency     string  `env:"CURRENCY" envDefault:"EUR"`
+	// SubscriberCheck fails messages to numbers which are invalid or absent in the virtual subscriber table
+	SubscriberCheck bool `env:"SUBSCRIBER_CHECK" envDefault:"false"`
+	// MaxBatchSize limits the number of messages in a bulk request
+	MaxBatchSize int `env:"MAX_BATCH_SIZE" envDefault:"10000"`
+	// DialectsDir has YAML/JSON files describing provider APIs to emulate
+	DialectsDir string `env:"DIALECTS_DIR"`
 }
 
 func ReadSettings() *Settings {
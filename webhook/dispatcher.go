@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go.etcd.io/bbolt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"smsgate-mock/data"
+	"smsgate-mock/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderId        = "X-Webhook-Id"
+	HeaderAttempt   = "X-Webhook-Attempt"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// batch is a maximum number of webhooks sent at one tick
+const batch = 100
+
+// Dispatcher sends webhooks persisted in the database, retrying failed ones with exponential backoff
+type Dispatcher struct {
+	cfg     *utils.Settings
+	db      *bbolt.DB
+	client  *http.Client
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func New(cfg *utils.Settings, db *bbolt.DB) *Dispatcher {
+	return &Dispatcher{
+		cfg:     cfg,
+		db:      db,
+		client:  &http.Client{Timeout: cfg.CallbackTimeout},
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Enqueue saves webhook for delivery
+func (d *Dispatcher) Enqueue(hook *data.Webhook) error {
+	if len(hook.Method) == 0 {
+		hook.Method = http.MethodPost
+	}
+	return hook.Save(d.db)
+}
+
+// Sign returns signature of the body: hex HMAC-SHA256 of "timestamp.body" with the sender's secret
+func Sign(secret string, timestamp int64, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "." + body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run sends due webhooks until Stop is called
+func (d *Dispatcher) Run() {
+	interval := d.cfg.ProcessInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer close(d.stopped)
+	for {
+		select {
+		case now := <-ticker.C:
+			d.process(now)
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Stop stops Run and waits for it to return
+func (d *Dispatcher) Stop() {
+	close(d.stop)
+	<-d.stopped
+}
+
+func (d *Dispatcher) process(now time.Time) {
+	hooks, err := (&data.Webhook{}).ListDue(d.db, now, batch)
+	if err != nil {
+		log.Printf("Can't load webhooks: %v", err)
+		return
+	}
+	var wg sync.WaitGroup
+	for _, hook := range hooks {
+		wg.Add(1)
+		go func(hook *data.Webhook) {
+			defer wg.Done()
+			d.attempt(hook)
+		}(hook)
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) attempt(hook *data.Webhook) {
+	sendErr := d.send(hook)
+	var err error
+	if sendErr == nil {
+		err = hook.Delivered(d.db)
+	} else if hook.Attempts+1 >= d.cfg.WebhookMaxAttempts {
+		log.Printf("Webhook %s to %s is dead after %d attempts: %v", hook.WebhookUuid, hook.Url, hook.Attempts+1, sendErr)
+		err = hook.Kill(d.db, sendErr.Error())
+	} else {
+		err = hook.Retry(d.db, sendErr.Error(), time.Now().Add(d.backoff(hook.Attempts)))
+	}
+	if err != nil {
+		log.Printf("Can't update webhook %s: %v", hook.WebhookUuid, err)
+	}
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.WebhookBackoff
+	for i := 0; i < attempts; i++ {
+		delay *= 2
+		if d.cfg.WebhookMaxBackoff > 0 && delay >= d.cfg.WebhookMaxBackoff {
+			return d.cfg.WebhookMaxBackoff
+		}
+	}
+	return delay
+}
+
+func (d *Dispatcher) send(hook *data.Webhook) error {
+	var body io.Reader
+	if len(hook.Body) > 0 {
+		body = strings.NewReader(hook.Body)
+	}
+	req, err := http.NewRequest(hook.Method, hook.Url, body)
+	if err != nil {
+		return fmt.Errorf("can't create request: %v", err)
+	}
+	if len(hook.ContentType) > 0 {
+		req.Header.Set("Content-Type", hook.ContentType)
+	}
+	for k, v := range hook.Headers {
+		req.Header.Set(k, v)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set(HeaderId, hook.WebhookUuid.String())
+	req.Header.Set(HeaderAttempt, strconv.Itoa(hook.Attempts+1))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	sender := &data.Sender{}
+	if err := sender.LoadById(d.db, hook.SenderUuid); err != nil {
+		log.Printf("Can't load sender to sign webhook %s: %v", hook.WebhookUuid, err)
+	} else if len(sender.Secret) > 0 {
+		req.Header.Set(HeaderSignature, Sign(sender.Secret, timestamp, hook.Body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}